@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"github.com/echocat/nsone_exporter/model"
+	"github.com/echocat/nsone_exporter/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"sync"
+	"time"
+)
+
+// NsoneAccount is one NSONE account which stats are exported by NsoneExporter.
+type NsoneAccount struct {
+	// Name is exported as 'account' label of every metric of this account.
+	Name            string
+	Client          *model.Client
+	NumberOfWorkers int
+	Settings        NsoneExportSettings
+}
+
+// nsoneAccountExporter collects the stats of exactly one NsoneAccount.
+type nsoneAccountExporter struct {
+	// account is the account this exporter was created for.
+	account       NsoneAccount
+	name          string
+	client        *model.Client
+	settings      NsoneExportSettings
+	workerPool    *utils.WorkerPool
+	collectErrors *prometheus.CounterVec
+	collecting    chan struct{}
+	// closed is only accessed while holding collecting.
+	closed bool
+	// zoneChangeTracker is shared with the account that replaces this one on reload.
+	zoneChangeTracker *zoneChangeTracker
+	// monitorStatusTracker is shared with the account that replaces this one on reload.
+	monitorStatusTracker *monitorStatusTracker
+	snapshotLock         sync.RWMutex
+
+	snapshot *nsoneSnapshot
+}
+
+// nsoneSnapshot is the result of one complete collection.
+type nsoneSnapshot struct {
+	points map[string]*prometheus.GaugeVec
+	// timestamps of points that are exported with an explicit timestamp.
+	timestamps map[prometheus.Metric]time.Time
+	successes  map[string]bool
+	up         float64
+	timestamp  time.Time
+}
+
+func newNsoneAccountExporter(account NsoneAccount, collectErrors *prometheus.CounterVec, zoneChanges *prometheus.CounterVec, monitorStatusChanges *prometheus.CounterVec) *nsoneAccountExporter {
+	return &nsoneAccountExporter{
+		account:              account,
+		name:                 account.Name,
+		client:               account.Client,
+		settings:             account.Settings,
+		workerPool:           utils.NewWorkerPool(account.NumberOfWorkers, account.NumberOfWorkers),
+		collectErrors:        collectErrors,
+		collecting:           make(chan struct{}, 1),
+		zoneChangeTracker:    newZoneChangeTracker(account.Name, zoneChanges),
+		monitorStatusTracker: newMonitorStatusTracker(account.Name, monitorStatusChanges),
+	}
+}
+
+// equals returns true if both accounts would be collected in exactly the same way.
+func (instance NsoneAccount) equals(other NsoneAccount) bool {
+	if instance.Name != other.Name || instance.NumberOfWorkers != other.NumberOfWorkers {
+		return false
+	}
+	if (instance.Client == nil) != (other.Client == nil) {
+		return false
+	}
+	if instance.Client != nil && instance.Client != other.Client && !clientOptionsEqual(instance.Client.Options(), other.Client.Options()) {
+		return false
+	}
+	return settingsEqual(instance.Settings, other.Settings)
+}
+
+// clientOptionsEqual compares everything of the given options but the observer which is
+// created for every account.
+func clientOptionsEqual(a model.ClientOptions, b model.ClientOptions) bool {
+	return a.ApiUri == b.ApiUri &&
+		a.AccessToken == b.AccessToken &&
+		a.Timeout == b.Timeout &&
+		a.MaximumNumberOfConcurrentConnections == b.MaximumNumberOfConcurrentConnections &&
+		a.Transport == b.Transport &&
+		stringsEqual(a.CaFiles, b.CaFiles) &&
+		a.Proxy == b.Proxy
+}
+
+func settingsEqual(a NsoneExportSettings, b NsoneExportSettings) bool {
+	if a.UsageGraph != b.UsageGraph ||
+		a.UsageOfAccount != b.UsageOfAccount ||
+		a.QpsOfAccount != b.QpsOfAccount ||
+		a.LogZoneChanges != b.LogZoneChanges ||
+		a.MonitorMetrics != b.MonitorMetrics {
+		return false
+	}
+	if len(a.DriftZoneFiles) != len(b.DriftZoneFiles) {
+		return false
+	}
+	for zone, file := range a.DriftZoneFiles {
+		if otherFile, ok := b.DriftZoneFiles[zone]; !ok || file != otherFile {
+			return false
+		}
+	}
+	return matchersEqual(a.UsageByHourFilter, b.UsageByHourFilter) &&
+		matchersEqual(a.UsageByDayFilter, b.UsageByDayFilter) &&
+		matchersEqual(a.UsageByMonthFilter, b.UsageByMonthFilter) &&
+		matchersEqual(a.UsageOfZonesFilter, b.UsageOfZonesFilter) &&
+		matchersEqual(a.UsageOfRecordsFilter, b.UsageOfRecordsFilter) &&
+		matchersEqual(a.QpsOfZonesFilter, b.QpsOfZonesFilter) &&
+		matchersEqual(a.QpsOfRecordsFilter, b.QpsOfRecordsFilter) &&
+		matchersEqual(a.InventoryOfZonesFilter, b.InventoryOfZonesFilter) &&
+		matchersEqual(a.InventoryOfRecordsFilter, b.InventoryOfRecordsFilter) &&
+		matchersEqual(a.InventoryOfAnswersFilter, b.InventoryOfAnswersFilter) &&
+		matchersEqual(a.PulsarFilter, b.PulsarFilter) &&
+		matchersEqual(a.MonitorsFilter, b.MonitorsFilter) &&
+		matchersEqual(a.DataFeedsFilter, b.DataFeedsFilter)
+}
+
+func matchersEqual(a model.Matcher, b model.Matcher) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.String() == b.String()
+}
+
+func stringsEqual(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// close releases the worker pool and the connections of this account after the collection
+// which is currently running (if any) is finished. The account does not collect anymore. If
+// removed is true the api metrics of this account are deleted, too.
+func (instance *nsoneAccountExporter) close(removed bool) {
+	go func() {
+		instance.collecting <- struct{}{}
+		defer func() { <-instance.collecting }()
+		instance.closed = true
+		instance.workerPool.Close()
+		instance.client.CloseIdleConnections()
+		if removed {
+			deleteApiMetricsOf(instance.name)
+		}
+	}()
+}
+
+func (instance *nsoneAccountExporter) currentSnapshot() *nsoneSnapshot {
+	instance.snapshotLock.RLock()
+	defer instance.snapshotLock.RUnlock()
+	return instance.snapshot
+}
+
+// collect fetches the stats from configured nsone and stores them as new snapshot. If ctx
+// is done before the collection is complete everything that was complete until then is
+// stored. If ctx is done while waiting for another collection the snapshot is kept.
+func (instance *nsoneAccountExporter) collect(ctx context.Context) {
+	select { // To prevent concurrent collections against nsone.
+	case instance.collecting <- struct{}{}:
+		defer func() { <-instance.collecting }()
+	case <-ctx.Done():
+		log.Warnf("Collecting account %s... SKIPPED! Got: %v", instance.name, ctx.Err())
+		return
+	}
+	if instance.closed {
+		log.Debugf("Collecting account %s... SKIPPED! It was replaced by a reload.", instance.name)
+		return
+	}
+
+	start := time.Now()
+	log.Infof("Collecting account %s...", instance.name)
+
+	target := newNsoneCollection(instance.name, instance.settings)
+	snapshot := &nsoneSnapshot{
+		points:    map[string]*prometheus.GaugeVec{},
+		successes: map[string]bool{},
+	}
+	zones, err := instance.client.GetZones(ctx, true)
+	unavailableZones := []string{}
+	if expandErr, ok := err.(*model.ExpandZonesError); ok {
+		target.expect("zones")
+		for zone, cause := range expandErr.Errors {
+			target.failed("zones", "/zones/{zone}", zone, cause)
+			unavailableZones = append(unavailableZones, zone)
+		}
+		err = nil
+	}
+	if err == nil {
+		target.expect("zones")
+		log.Infof("Found %d active zones in account %s.", len(*zones), instance.name)
+
+		if err := exportUnknownRecordTypesOf(zones, target); err != nil {
+			target.failed("zones", "/zones/{zone}", "", err)
+		}
+		instance.zoneChangeTracker.track(zones, unavailableZones, instance.settings.InventoryOfZonesFilter, instance.settings.LogZoneChanges, target)
+
+		instance.exportZoneInventoryIfRequired(zones, target)
+		instance.exportRecordInventoryIfRequired(zones, target)
+		instance.exportAnswerInventoryIfRequired(ctx, zones, target)
+		instance.exportDriftIfRequired(zones, target)
+		instance.exportUsageIfRequired(ctx, zones, target)
+		instance.exportQpsIfRequired(ctx, zones, target)
+		instance.exportPulsarIfRequired(ctx, target)
+		instance.exportMonitoringIfRequired(ctx, target)
+		instance.exportDataFeedsIfRequired(ctx, zones, target)
+
+		log.Infof("%d tasks submitted for account %s.", len(target.submittedTasks()), instance.name)
+
+		errs := target.Wait(ctx)
+		for _, cErr := range errs {
+			log.Warnf("Collecting of %s failed for zone '%s' of account %s: %v", cErr.endpoint, cErr.zone, instance.name, cErr.err)
+			instance.collectErrors.WithLabelValues(instance.name, cErr.endpoint, cErr.zone).Inc()
+		}
+		snapshot.points = target.points
+		snapshot.timestamps = target.timestamps
+		snapshot.successes = target.successes
+		snapshot.up = 1
+		snapshot.timestamp = time.Now()
+		log.Infof("Collecting account %s... DONE! (duration: %v, failed tasks: %d)", instance.name, snapshot.timestamp.Sub(start), len(errs))
+	} else {
+		instance.collectErrors.WithLabelValues(instance.name, "/zones", "").Inc()
+		snapshot.timestamp = time.Now()
+		log.Errorf("Collecting account %s... FAILED! (duration: %v) Got: %v", instance.name, snapshot.timestamp.Sub(start), err)
+	}
+
+	instance.snapshotLock.Lock()
+	instance.snapshot = snapshot
+	instance.snapshotLock.Unlock()
+}
+
+// exportUnknownRecordTypesOf exports the number of records by type of the given (already
+// expanded) zones which types are not known by this exporter.
+func exportUnknownRecordTypesOf(zones *model.Zones, target *nsoneCollection) error {
+	numberOfRecords := map[model.RecordType]int{}
+	for _, zone := range *zones {
+		for _, record := range zone.Records {
+			if !record.Type.IsKnown() {
+				numberOfRecords[record.Type]++
+			}
+		}
+	}
+	for recordType, count := range numberOfRecords {
+		if err := target.setPointWith("unknown_record_types", float64(count), prometheus.Labels{"type": recordType.String()}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
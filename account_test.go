@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/echocat/nsone_exporter/model"
+)
+
+func TestCollectCountsRecordsOfUnknownTypesOnce(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	server.AddZone(&model.Zone{Name: "new.org", TTL: 3600, Records: []*model.Record{
+		{Name: "a.new.org", Type: model.RecordType("TYPE65534"), ShortAnswers: []string{`\# 0`}, TTL: 60},
+		{Name: "b.new.org", Type: model.RecordType("TYPE65534"), ShortAnswers: []string{`\# 0`}, TTL: 60},
+		{Name: "c.new.org", Type: model.RecordType("X.Y"), ShortAnswers: []string{"foo"}, TTL: 60},
+	}})
+	exporter := NewNsoneExporter([]NsoneAccount{newTestAccount(t, server, "a", newTestSettings())})
+
+	gather(t, exporter)
+	samples := gather(t, exporter)
+
+	assertSamples(t, samples, map[string]float64{
+		`nsone_up{account="a"}`: 1,
+		`nsone_unknown_record_types{account="a",type="TYPE65534"}`: 2,
+		`nsone_unknown_record_types{account="a",type="X.Y"}`:       1,
+	})
+	refuteSamples(t, samples, `nsone_unknown_record_types{account="a",type="A"}`)
+}
+
+func TestAccountEqualsComparesClientOptionsAndSettings(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	account := newTestAccount(t, server, "a", newTestSettings())
+
+	same := newTestAccount(t, server, "a", newTestSettings())
+	if !account.equals(same) {
+		t.Errorf("Expected accounts with equal options and settings to be equal.")
+	}
+
+	otherFilter := newTestAccount(t, server, "a", newTestSettings())
+	otherFilter.Settings.QpsOfRecordsFilter = model.NewRegexpOrPanic("^A www")
+	otherDriftFiles := newTestAccount(t, server, "a", newTestSettings())
+	otherDriftFiles.Settings.DriftZoneFiles = map[string]string{"example.com": "example.com.zone"}
+	otherWorkers := newTestAccount(t, server, "a", newTestSettings())
+	otherWorkers.NumberOfWorkers++
+	options := server.ClientOptions()
+	options.Timeout = time.Minute
+	otherClient := newTestAccount(t, server, "a", newTestSettings())
+	otherClient.Client = newTestClient(t, options)
+	for name, other := range map[string]NsoneAccount{
+		"filter":      otherFilter,
+		"drift files": otherDriftFiles,
+		"workers":     otherWorkers,
+		"client":      otherClient,
+	} {
+		if account.equals(other) {
+			t.Errorf("Expected accounts with other %s to be different.", name)
+		}
+	}
+}
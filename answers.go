@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"github.com/echocat/nsone_exporter/model"
+	"github.com/prometheus/client_golang/prometheus"
+	"strings"
+)
+
+func appendAnswerInventory(to *map[string]*prometheus.GaugeVec) {
+	appendGaugeWith(to, "answer_up", "Is the answer of records up? Metadata that is provided by data feeds is not exported.", "zone", "record", "recordType", "answer", "region")
+	appendGaugeWith(to, "answer_weight", "Weight of the answer of records.", "zone", "record", "recordType", "answer", "region")
+	appendGaugeWith(to, "answer_priority", "Priority of the answer of records.", "zone", "record", "recordType", "answer", "region")
+	appendGaugeWith(to, "record_filter_info", "Filter chain of records (enabled filters in order). Always 1.", "zone", "record", "recordType", "filters")
+}
+
+// exportAnswerInventoryIfRequired exports the metadata of every answer and the filter chain
+// of the records of the given (already expanded) zones. Every matching record is retrieved
+// by itself because the zone details do not contain this information.
+func (instance *nsoneAccountExporter) exportAnswerInventoryIfRequired(ctx context.Context, zones *model.Zones, target *nsoneCollection) {
+	if !instance.settings.InventoryOfAnswersFilter.HasValue() {
+		return
+	}
+	target.expect("answer_inventory")
+	for _, zone := range *zones {
+		for _, record := range zone.Records {
+			zone, record := zone, record
+			fullRecord := record.Type.String() + " " + record.Name
+			if len(record.Link) > 0 || !instance.settings.InventoryOfAnswersFilter.MatchString(fullRecord) {
+				continue
+			}
+			target.Submit(ctx, instance.workerPool, "answer_inventory", "/zones/{zone}/{record}/{type}", zone.Name, func() error {
+				fullRecord, err := instance.client.GetRecord(ctx, zone.Name, record.Name, record.Type)
+				if err != nil {
+					return err
+				}
+				return exportAnswerInventoryOf(zone, fullRecord, target)
+			})
+		}
+	}
+}
+
+func exportAnswerInventoryOf(zone *model.Zone, record *model.Record, target *nsoneCollection) error {
+	filters := []string{}
+	for _, filter := range record.Filters {
+		if !filter.Disabled {
+			filters = append(filters, filter.Filter)
+		}
+	}
+	err := target.setPointWith("record_filter_info", 1, prometheus.Labels{
+		"zone":       zone.Name,
+		"record":     record.Name,
+		"recordType": record.Type.String(),
+		"filters":    strings.Join(filters, ","),
+	})
+	if err != nil {
+		return err
+	}
+	for _, answer := range record.Answers {
+		answerLabels := prometheus.Labels{
+			"zone":       zone.Name,
+			"record":     record.Name,
+			"recordType": record.Type.String(),
+			"answer":     strings.Join(answer.Answer, " "),
+			"region":     answer.Region,
+		}
+		up, weight, priority := metaValuesOf(record, answer)
+		values := map[string]*model.MetaValue{
+			"answer_weight":   weight,
+			"answer_priority": priority,
+		}
+		if up == nil {
+			// Answers without any up metadata are always served by NSONE.
+			up = &model.MetaValue{Value: true}
+		}
+		values["answer_up"] = up
+		for name, value := range values {
+			if number, ok := value.Number(); ok {
+				if err := target.setPointWith(name, number, answerLabels); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// metaValuesOf returns the metadata NSONE applies to the given answer. Metadata of the answer
+// itself takes precedence over the metadata of its region which takes precedence over the
+// metadata of the record.
+func metaValuesOf(record *model.Record, answer *model.Answer) (up *model.MetaValue, weight *model.MetaValue, priority *model.MetaValue) {
+	metas := []*model.Meta{answer.Meta}
+	if region, ok := record.Regions[answer.Region]; ok && region != nil {
+		metas = append(metas, region.Meta)
+	}
+	metas = append(metas, record.Meta)
+	for i := len(metas) - 1; i >= 0; i-- {
+		meta := metas[i]
+		if meta == nil {
+			continue
+		}
+		if meta.Up != nil {
+			up = meta.Up
+		}
+		if meta.Weight != nil {
+			weight = meta.Weight
+		}
+		if meta.Priority != nil {
+			priority = meta.Priority
+		}
+	}
+	return
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/echocat/nsone_exporter/model"
+)
+
+const testSteeredRecord = `{
+	"domain": "lb.steer.net", "type": "A", "id": "r1", "ttl": 60,
+	"meta": {"weight": 1},
+	"regions": {"east": {"meta": {"up": false, "priority": 2}}},
+	"filters": [
+		{"filter": "up", "config": {}},
+		{"filter": "geotarget_country", "disabled": true},
+		{"filter": "shuffle"}
+	],
+	"answers": [
+		{"id": "a1", "answer": ["1.1.1.1"], "region": "east", "meta": {"weight": 5}},
+		{"id": "a2", "answer": ["2.2.2.2"], "meta": {"up": {"feed": "f1"}}},
+		{"id": "a3", "answer": ["3.3.3.3"], "meta": {"up": "1", "georegion": ["US-EAST"]}}
+	]
+}`
+
+func TestCollectExportsAnswersOfMatchingRecords(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	record := &model.Record{}
+	if err := json.Unmarshal([]byte(testSteeredRecord), record); err != nil {
+		t.Fatal(err)
+	}
+	server.AddZone(&model.Zone{Name: "steer.net", TTL: 3600, Records: []*model.Record{record}})
+	settings := newTestSettings()
+	settings.InventoryOfAnswersFilter = model.NewRegexpOrPanic("^A lb\\.")
+	exporter := NewNsoneExporter([]NsoneAccount{newTestAccount(t, server, "a", settings)})
+
+	samples := gather(t, exporter)
+
+	assertSamples(t, samples, map[string]float64{
+		`nsone_collector_success{account="a",collector="answer_inventory"}`:                                                1,
+		`nsone_record_filter_info{account="a",filters="up,shuffle",record="lb.steer.net",recordType="A",zone="steer.net"}`: 1,
+		// Meta of the region and of the record are inherited by the answer.
+		`nsone_answer_up{account="a",answer="1.1.1.1",record="lb.steer.net",recordType="A",region="east",zone="steer.net"}`:       0,
+		`nsone_answer_priority{account="a",answer="1.1.1.1",record="lb.steer.net",recordType="A",region="east",zone="steer.net"}`: 2,
+		`nsone_answer_weight{account="a",answer="1.1.1.1",record="lb.steer.net",recordType="A",region="east",zone="steer.net"}`:   5,
+		`nsone_answer_weight{account="a",answer="2.2.2.2",record="lb.steer.net",recordType="A",region="",zone="steer.net"}`:       1,
+		`nsone_answer_up{account="a",answer="3.3.3.3",record="lb.steer.net",recordType="A",region="",zone="steer.net"}`:           1,
+	})
+	// The state of an answer that is driven by a data feed is unknown.
+	refuteSamples(t, samples, `nsone_answer_up{account="a",answer="2.2.2.2"`)
+}
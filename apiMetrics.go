@@ -0,0 +1,98 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	apiRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "api",
+		Name:      "requests_total",
+		Help:      "Number of requests against NSONE by endpoint and HTTP status code ('error' if no response was received).",
+	}, []string{"account", "endpoint", "code"})
+	apiRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "api",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of requests against NSONE by endpoint.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"account", "endpoint"})
+	apiRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "api",
+		Name:      "retries_total",
+		Help:      "Number of retried requests against NSONE by endpoint.",
+	}, []string{"account", "endpoint"})
+
+	// apiSeries contains the labels (endpoint and code) of every series of the api metrics
+	// by account to be able to delete them if an account is removed.
+	apiSeries     = map[string]map[apiSeriesKey]bool{}
+	apiSeriesLock sync.Mutex
+)
+
+type apiSeriesKey struct {
+	endpoint string
+	// code is empty for the series which have no code label.
+	code string
+}
+
+// apiObserver records the requests of the model.Client of one account as Prometheus metrics.
+// It implements model.ClientObserver.
+type apiObserver struct {
+	account string
+}
+
+func newApiObserver(account string) *apiObserver {
+	return &apiObserver{
+		account: account,
+	}
+}
+
+func (instance *apiObserver) ObserveRequest(endpoint string, statusCode int, duration time.Duration) {
+	code := "error"
+	if statusCode > 0 {
+		code = strconv.Itoa(statusCode)
+	}
+	apiSeriesLock.Lock()
+	defer apiSeriesLock.Unlock()
+	instance.remember(endpoint, code)
+	instance.remember(endpoint, "")
+	apiRequests.WithLabelValues(instance.account, endpoint, code).Inc()
+	apiRequestDuration.WithLabelValues(instance.account, endpoint).Observe(duration.Seconds())
+}
+
+func (instance *apiObserver) ObserveRetry(endpoint string) {
+	apiSeriesLock.Lock()
+	defer apiSeriesLock.Unlock()
+	instance.remember(endpoint, "")
+	apiRetries.WithLabelValues(instance.account, endpoint).Inc()
+}
+
+// remember has to be called while holding apiSeriesLock.
+func (instance *apiObserver) remember(endpoint string, code string) {
+	series := apiSeries[instance.account]
+	if series == nil {
+		series = map[apiSeriesKey]bool{}
+		apiSeries[instance.account] = series
+	}
+	series[apiSeriesKey{endpoint: endpoint, code: code}] = true
+}
+
+// deleteApiMetricsOf deletes every series of the api metrics of the given account.
+func deleteApiMetricsOf(account string) {
+	apiSeriesLock.Lock()
+	defer apiSeriesLock.Unlock()
+	for key := range apiSeries[account] {
+		if key.code != "" {
+			apiRequests.DeleteLabelValues(account, key.endpoint, key.code)
+		} else {
+			apiRequestDuration.DeleteLabelValues(account, key.endpoint)
+			apiRetries.DeleteLabelValues(account, key.endpoint)
+		}
+	}
+	delete(apiSeries, account)
+}
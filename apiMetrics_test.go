@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/echocat/nsone_exporter/nsonetest"
+)
+
+func TestClientRecordsRequestsAsApiMetrics(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	server.SetRateLimit(100, time.Second)
+	server.RateLimited("/zones", 1)
+	server.Inject("/zones/other.org", nsonetest.Fault{StatusCode: http.StatusInternalServerError})
+	client := newObservedTestAccount(t, server, "api-metrics", newTestSettings()).Client
+	defer deleteApiMetricsOf("api-metrics")
+
+	if _, err := client.GetZones(context.Background(), false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetZone(context.Background(), "other.org"); err == nil {
+		t.Fatal("Expected an error for status 500 but got none.")
+	}
+
+	assertSamples(t, gather(t, apiRequests), map[string]float64{
+		`nsone_api_requests_total{account="api-metrics",code="200",endpoint="/zones"}`:        1,
+		`nsone_api_requests_total{account="api-metrics",code="429",endpoint="/zones"}`:        1,
+		`nsone_api_requests_total{account="api-metrics",code="500",endpoint="/zones/{zone}"}`: 1,
+	})
+	assertSamples(t, gather(t, apiRequestDuration), map[string]float64{
+		`nsone_api_request_duration_seconds{account="api-metrics",endpoint="/zones"}`:        2,
+		`nsone_api_request_duration_seconds{account="api-metrics",endpoint="/zones/{zone}"}`: 1,
+	})
+	assertSamples(t, gather(t, apiRetries), map[string]float64{
+		`nsone_api_retries_total{account="api-metrics",endpoint="/zones"}`: 1,
+	})
+}
+
+func TestDeleteApiMetricsOfDeletesEverySeriesOfAccount(t *testing.T) {
+	observer, other := newApiObserver("api-deleted"), newApiObserver("api-other")
+	defer deleteApiMetricsOf("api-other")
+	observer.ObserveRequest("/zones", 200, time.Millisecond)
+	observer.ObserveRequest("/zones", 0, time.Millisecond)
+	observer.ObserveRetry("/zones")
+	other.ObserveRequest("/zones", 200, time.Millisecond)
+
+	deleteApiMetricsOf("api-deleted")
+
+	requests, durations, retries := gather(t, apiRequests), gather(t, apiRequestDuration), gather(t, apiRetries)
+	refuteSamples(t, requests, `nsone_api_requests_total{account="api-deleted"`)
+	refuteSamples(t, durations, `nsone_api_request_duration_seconds{account="api-deleted"`)
+	refuteSamples(t, retries, `nsone_api_retries_total{account="api-deleted"`)
+	assertSamples(t, requests, map[string]float64{
+		`nsone_api_requests_total{account="api-other",code="200",endpoint="/zones"}`: 1,
+	})
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/echocat/nsone_exporter/model"
+	"github.com/echocat/nsone_exporter/utils"
+	"github.com/prometheus/common/log"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// backfillFamily is one metric family that is written by backfill.
+type backfillFamily struct {
+	name   string
+	help   string
+	lock   sync.Mutex
+	series map[string][]model.UsageBucket
+}
+
+func newBackfillFamily(name string, help string) *backfillFamily {
+	return &backfillFamily{
+		name:   namespace + "_" + name + usageGraphSuffix,
+		help:   help,
+		series: map[string][]model.UsageBucket{},
+	}
+}
+
+func (instance *backfillFamily) add(usage *model.Usage, account string, zone string, record string, recordType model.RecordType) {
+	labels := []string{
+		"account=" + quoteLabelValue(account),
+	}
+	if len(zone) > 0 {
+		labels = append(labels, "zone="+quoteLabelValue(zone))
+	}
+	if len(record) > 0 {
+		labels = append(labels, "record="+quoteLabelValue(record), "recordType="+quoteLabelValue(recordType.String()))
+	}
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	instance.series[strings.Join(labels, ",")] = usage.CompleteBuckets()
+}
+
+func (instance *backfillFamily) writeTo(w io.Writer) error {
+	if len(instance.series) <= 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", instance.name, instance.help, instance.name); err != nil {
+		return err
+	}
+	labels := []string{}
+	for candidate := range instance.series {
+		labels = append(labels, candidate)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		for _, bucket := range instance.series[label] {
+			_, err := fmt.Fprintf(w, "%s{%s} %s %d\n", instance.name, label, strconv.FormatFloat(bucket.Queries, 'g', -1, 64), bucket.Start.Unix())
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// backfillFamilies are all metric families that are written by backfill.
+type backfillFamilies struct {
+	account *backfillFamily
+	zones   *backfillFamily
+	records *backfillFamily
+}
+
+// backfill writes the complete buckets of the monthly usage graphs of the given accounts
+// as OpenMetrics to output (stdout if empty or '-'). The result could be imported with
+// 'promtool tsdb create-blocks-from openmetrics'.
+func backfill(ctx context.Context, accounts []NsoneAccount, output string) error {
+	families := &backfillFamilies{
+		account: newBackfillFamily("usage_account_monthly", "Queries of whole account per bucket of the usage graph by month."),
+		zones:   newBackfillFamily("usage_zones_monthly", "Queries of zones per bucket of the usage graph by month."),
+		records: newBackfillFamily("usage_records_monthly", "Queries of records per bucket of the usage graph by month."),
+	}
+	for _, account := range accounts {
+		log.Infof("Backfilling account %s...", account.Name)
+		if err := backfillAccount(ctx, account, families); err != nil {
+			return err
+		}
+	}
+
+	write := func(w io.Writer) error {
+		buffered := bufio.NewWriter(w)
+		for _, family := range []*backfillFamily{families.account, families.zones, families.records} {
+			if err := family.writeTo(buffered); err != nil {
+				return fmt.Errorf("Could not write %s. Cause: %v", family.name, err)
+			}
+		}
+		if _, err := fmt.Fprint(buffered, "# EOF\n"); err != nil {
+			return err
+		}
+		return buffered.Flush()
+	}
+	if len(output) <= 0 || output == "-" {
+		return write(os.Stdout)
+	}
+	if err := writeFileAtomically(output, write); err != nil {
+		return fmt.Errorf("Could not write backfill to %s. Cause: %v", output, err)
+	}
+	return nil
+}
+
+func backfillAccount(ctx context.Context, account NsoneAccount, families *backfillFamilies) error {
+	settings := account.Settings
+	client := account.Client
+	if settings.UsageOfAccount && settings.UsageByMonthFilter.MatchString("account") {
+		usage, err := client.GetAccountUsage(ctx, model.P_MONTHLY)
+		if err != nil {
+			return fmt.Errorf("Could not get usage of account %s. Cause: %v", account.Name, err)
+		}
+		families.account.add(usage, account.Name, "", "", model.RT_NONE)
+	}
+	if settings.UsageOfZonesFilter.HasValue() {
+		usages, err := client.GetZonesUsage(ctx, model.P_MONTHLY)
+		if err != nil {
+			return fmt.Errorf("Could not get usage of zones of account %s. Cause: %v", account.Name, err)
+		}
+		for _, usage := range *usages {
+			if settings.UsageByMonthFilter.MatchString(usage.Zone) && settings.UsageOfZonesFilter.MatchString(usage.Zone) {
+				families.zones.add(usage, account.Name, usage.Zone, "", model.RT_NONE)
+			}
+		}
+	}
+	if settings.UsageOfRecordsFilter.HasValue() {
+		zones, err := client.GetZones(ctx, false)
+		if err != nil {
+			return fmt.Errorf("Could not get zones of account %s. Cause: %v", account.Name, err)
+		}
+		pool := utils.NewWorkerPool(account.NumberOfWorkers, account.NumberOfWorkers)
+		defer pool.Close()
+		futures := utils.WorkerFutures{}
+		for _, zone := range *zones {
+			if len(zone.Link) > 0 || !settings.UsageByMonthFilter.MatchString(zone.Name) || !settings.UsageOfRecordsFilter.MatchString(zone.Name) {
+				continue
+			}
+			zoneName := zone.Name
+			futures.Submit(ctx, pool, func() error {
+				usages, err := client.GetRecordsUsage(ctx, zoneName, model.P_MONTHLY)
+				if err != nil {
+					return fmt.Errorf("Could not get usage of records of zone %s of account %s. Cause: %v", zoneName, account.Name, err)
+				}
+				for _, usage := range *usages {
+					fullRecord := usage.Type.String() + " " + usage.Domain
+					if settings.UsageByMonthFilter.MatchString(fullRecord) && settings.UsageOfRecordsFilter.MatchString(fullRecord) {
+						families.records.add(usage, account.Name, usage.Zone, usage.Domain, usage.Type)
+					}
+				}
+				return nil
+			})
+		}
+		if err := futures.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func quoteLabelValue(value string) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	value = strings.Replace(value, "\n", `\n`, -1)
+	value = strings.Replace(value, `"`, `\"`, -1)
+	return `"` + value + `"`
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/echocat/nsone_exporter/model"
+)
+
+func TestBackfillWritesCompleteBucketsOfMonthlyUsageGraphs(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	server.SetZoneUsage("example.com", model.P_MONTHLY, 40, [][]float64{{1500000000, 20}, {1500003600, 15}, {1500007200, 5}})
+	server.SetRecordUsage("example.com", "www.example.com", model.RT_A, model.P_MONTHLY, 10, [][]float64{{1500000000, 7}, {1500003600, 3}})
+	settings := newTestSettings()
+	settings.UsageOfZonesFilter = model.NewRegexpOrPanic("^example")
+	settings.UsageOfRecordsFilter = model.NewRegexpOrPanic("example")
+	directory, err := ioutil.TempDir("", "nsone_exporter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+	output := filepath.Join(directory, "backfill.om")
+
+	if err := backfill(context.Background(), []NsoneAccount{newTestAccount(t, server, "a", settings)}, output); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples := []string{}
+	for _, line := range strings.Split(strings.TrimSuffix(string(content), "\n"), "\n") {
+		if !strings.HasPrefix(line, "# HELP ") && !strings.HasPrefix(line, "# TYPE ") {
+			samples = append(samples, line)
+		}
+	}
+	expected := []string{
+		`nsone_usage_account_monthly_last_bucket{account="a"} 2 1500000000`,
+		`nsone_usage_zones_monthly_last_bucket{account="a",zone="example.com"} 20 1500000000`,
+		`nsone_usage_zones_monthly_last_bucket{account="a",zone="example.com"} 15 1500003600`,
+		`nsone_usage_records_monthly_last_bucket{account="a",zone="example.com",record="www.example.com",recordType="A"} 7 1500000000`,
+		"# EOF",
+	}
+	if strings.Join(samples, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected samples:\n%s\nbut got:\n%s", strings.Join(expected, "\n"), content)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(directory, ".*")); len(leftovers) > 0 {
+		t.Errorf("Expected no temporary files but got %v.", leftovers)
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/echocat/nsone_exporter/model"
+	"github.com/echocat/nsone_exporter/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"strings"
+	"sync"
+	"time"
+)
+
+// usageGraphSuffix is appended to the name of a usage point to get the name of the
+// point that contains the last complete bucket of the usage graph.
+const usageGraphSuffix = "_last_bucket"
+
+// nsoneCollection holds the state of exactly one collection. Every collection
+// writes into its own points so a running collection never modifies a snapshot
+// that is currently delivered.
+type nsoneCollection struct {
+	account    string
+	usageGraph model.UsageGraphMode
+	points     map[string]*prometheus.GaugeVec
+	timestamps map[prometheus.Metric]time.Time
+	pointsLock sync.Mutex
+
+	tasksLock sync.Mutex
+	tasks     []*collectTask
+	// submitted is signaled every time a task is submitted so Wait could enqueue it.
+	submitted chan struct{}
+	// sealed is true after Wait stopped to enqueue tasks. Tasks that are submitted
+	// afterwards are ignored.
+	sealed bool
+
+	errorsLock sync.Mutex
+	errors     []*collectError
+	successes  map[string]bool
+	// finished is true after Wait. Tasks that are still running afterwards (because
+	// they were abandoned) must not modify points, timestamps or successes anymore
+	// because they are already published as snapshot.
+	finished bool
+}
+
+// collectTask is a submitted task of a collection.
+type collectTask struct {
+	pool      *utils.WorkerPool
+	future    *utils.WorkerFuture
+	collector string
+	endpoint  string
+	zone      string
+}
+
+// collectError is a failed task of a collection.
+type collectError struct {
+	collector string
+	endpoint  string
+	zone      string
+	err       error
+}
+
+func (instance collectError) Error() string {
+	return instance.err.Error()
+}
+
+func newNsoneCollection(account string, settings NsoneExportSettings) *nsoneCollection {
+	return &nsoneCollection{
+		account:    account,
+		usageGraph: settings.UsageGraph,
+		points:     newPointsFor(settings),
+		timestamps: map[prometheus.Metric]time.Time{},
+		successes:  map[string]bool{},
+		submitted:  make(chan struct{}, 1),
+	}
+}
+
+// Submit submits the given task to the given pool. If the task fails the error is captured
+// for the given collector, endpoint (templated like '/stats/qps/{zone}') and zone. Tasks could
+// submit follow-up tasks (like the jobs of a listed app) which are waited for, too. The tasks
+// are enqueued by Wait so a running task never blocks on a full pool.
+func (instance *nsoneCollection) Submit(ctx context.Context, pool *utils.WorkerPool, collector string, endpoint string, zone string, task utils.WorkerTask) {
+	instance.tasksLock.Lock()
+	defer instance.tasksLock.Unlock()
+	if instance.sealed {
+		return
+	}
+	instance.expect(collector)
+	instance.tasks = append(instance.tasks, &collectTask{
+		pool:      pool,
+		future:    utils.NewWorkerFutureFor(ctx, task),
+		collector: collector,
+		endpoint:  endpoint,
+		zone:      zone,
+	})
+	select {
+	case instance.submitted <- struct{}{}:
+	default:
+	}
+}
+
+func (instance *nsoneCollection) submittedTasks() []*collectTask {
+	instance.tasksLock.Lock()
+	defer instance.tasksLock.Unlock()
+	return instance.tasks
+}
+
+// expect marks the given collector as part of this collection. It is successful until
+// one of its tasks fails.
+func (instance *nsoneCollection) expect(collector string) {
+	instance.errorsLock.Lock()
+	defer instance.errorsLock.Unlock()
+	if instance.finished {
+		return
+	}
+	if _, exists := instance.successes[collector]; !exists {
+		instance.successes[collector] = true
+	}
+}
+
+func (instance *nsoneCollection) failed(collector string, endpoint string, zone string, err error) {
+	instance.errorsLock.Lock()
+	defer instance.errorsLock.Unlock()
+	if instance.finished {
+		return
+	}
+	instance.errors = append(instance.errors, &collectError{
+		collector: collector,
+		endpoint:  endpoint,
+		zone:      zone,
+		err:       err,
+	})
+	instance.successes[collector] = false
+}
+
+// Wait enqueues and waits for all submitted tasks (including their follow-up tasks) and
+// returns the errors of all failed tasks. If ctx is done before, every task that is not
+// done yet fails with the error of ctx and everything that is complete is kept.
+func (instance *nsoneCollection) Wait(ctx context.Context) []*collectError {
+	enqueued, waited := 0, 0
+loop:
+	for tasks := instance.submittedTasks(); waited < len(tasks); tasks = instance.submittedTasks() {
+		for ; enqueued < len(tasks); enqueued++ {
+			tasks[enqueued].pool.Enqueue(tasks[enqueued].future)
+		}
+		select {
+		case <-tasks[waited].future.Done():
+			waited++
+		case <-instance.submitted:
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	instance.tasksLock.Lock()
+	instance.sealed = true
+	tasks := instance.tasks
+	instance.tasksLock.Unlock()
+	for _, task := range tasks {
+		if !task.future.IsDone() {
+			instance.failed(task.collector, task.endpoint, task.zone, ctx.Err())
+		} else if err := task.future.Wait(context.Background()); err != nil {
+			instance.failed(task.collector, task.endpoint, task.zone, err)
+		}
+	}
+	instance.pointsLock.Lock()
+	defer instance.pointsLock.Unlock()
+	instance.errorsLock.Lock()
+	defer instance.errorsLock.Unlock()
+	instance.finished = true
+	return instance.errors
+}
+
+// setUsagePoint sets the queries of the given usage as point with the given name. If the
+// usage graph is exported the last complete bucket of the graph is set as well.
+func (instance *nsoneCollection) setUsagePoint(name string, usage *model.Usage, zone string, record string, recordType model.RecordType) error {
+	instance.pointsLock.Lock() // To protect metrics from concurrent sets on points.
+	defer instance.pointsLock.Unlock()
+	if instance.finished {
+		return nil
+	}
+	gauge, err := instance.pointFor(name, zone, record, recordType)
+	if err != nil {
+		return err
+	}
+	gauge.Set(usage.Queries)
+	if !instance.usageGraph.IsEnabled() {
+		return nil
+	}
+	bucket, ok := usage.LastCompleteBucket()
+	if !ok {
+		return nil
+	}
+	gauge, err = instance.pointFor(name+usageGraphSuffix, zone, record, recordType)
+	if err != nil {
+		return err
+	}
+	gauge.Set(bucket.Queries)
+	if instance.usageGraph == model.UG_TIMESTAMPS {
+		instance.timestamps[gauge] = bucket.Start
+	}
+	return nil
+}
+
+func (instance *nsoneCollection) setPoint(name string, value float64, zone string, record string, recordType model.RecordType) error {
+	instance.pointsLock.Lock() // To protect metrics from concurrent sets on points.
+	defer instance.pointsLock.Unlock()
+	if instance.finished {
+		return nil
+	}
+	gauge, err := instance.pointFor(name, zone, record, recordType)
+	if err != nil {
+		return err
+	}
+	gauge.Set(value)
+	return nil
+}
+
+// pointFor returns the point with the given name for the given zone and record. Has to be
+// called while holding pointsLock.
+func (instance *nsoneCollection) pointFor(name string, zone string, record string, recordType model.RecordType) (prometheus.Gauge, error) {
+	labels := prometheus.Labels{
+		"account": instance.account,
+	}
+	if strings.HasSuffix(name, "_zones") || strings.Contains(name, "_zones_") {
+		labels["zone"] = zone
+	}
+	if strings.HasSuffix(name, "_records") || strings.Contains(name, "_records_") {
+		labels["zone"] = zone
+		labels["record"] = record
+		labels["recordType"] = recordType.String()
+	}
+	gaugeVec := instance.points[name]
+	if gaugeVec == nil {
+		return nil, fmt.Errorf("Try to set point with name %s but it was not crated before.", name)
+	}
+	gauge, err := gaugeVec.GetMetricWith(labels)
+	if err != nil {
+		return nil, fmt.Errorf("Try to set point %s but got: %v", name, err)
+	}
+	return gauge, nil
+}
+
+// setPointWith sets the point with the given name and the given labels in addition to the
+// 'account' label.
+func (instance *nsoneCollection) setPointWith(name string, value float64, labels prometheus.Labels) error {
+	instance.pointsLock.Lock() // To protect metrics from concurrent sets on points.
+	defer instance.pointsLock.Unlock()
+	if instance.finished {
+		return nil
+	}
+	allLabels := prometheus.Labels{
+		"account": instance.account,
+	}
+	for key, labelValue := range labels {
+		allLabels[key] = labelValue
+	}
+	gaugeVec := instance.points[name]
+	if gaugeVec == nil {
+		return fmt.Errorf("Try to set point with name %s but it was not crated before.", name)
+	}
+	gauge, err := gaugeVec.GetMetricWith(allLabels)
+	if err != nil {
+		return fmt.Errorf("Try to set point %s but got: %v", name, err)
+	}
+	gauge.Set(value)
+	return nil
+}
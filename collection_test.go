@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/echocat/nsone_exporter/model"
+	"github.com/echocat/nsone_exporter/utils"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestCollectionIgnoresAbandonedTasksAfterWait(t *testing.T) {
+	pool := utils.NewWorkerPool(1, 1)
+	defer pool.Close()
+	collection := newNsoneCollection("a", newTestSettings())
+	release := make(chan struct{})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	abandoned := make(chan error, 1)
+	collection.Submit(ctx, pool, "qps_zones", "/stats/qps/{zone}", "example.com", func() error {
+		defer close(abandoned)
+		<-release
+		collection.Submit(ctx, pool, "late", "/stats/qps/{zone}", "example.com", func() error {
+			return nil
+		})
+		collection.expect("late")
+		if err := collection.setPoint("qps_zones", 1, "example.com", "", ""); err != nil {
+			return err
+		}
+		if err := collection.setPointWith("qps_account", 2, prometheus.Labels{}); err != nil {
+			return err
+		}
+		collection.failed("qps_records", "/stats/qps/{zone}", "example.com", errors.New("late"))
+		return nil
+	})
+
+	errs := collection.Wait(ctx)
+	close(release)
+	<-abandoned
+
+	if len(errs) != 1 || errs[0].collector != "qps_zones" {
+		t.Errorf("Expected only the abandoned task to fail but got %v.", errs)
+	}
+	if len(collection.successes) != 1 || collection.successes["qps_zones"] {
+		t.Errorf("Expected only the failed collector qps_zones but got %v.", collection.successes)
+	}
+	for name, point := range collection.points {
+		metrics := make(chan prometheus.Metric, 10)
+		point.Collect(metrics)
+		close(metrics)
+		for range metrics {
+			t.Errorf("Expected no point %s after the collection was finished but there is one.", name)
+		}
+	}
+}
+
+func TestCollectionWaitsForFollowUpTasks(t *testing.T) {
+	pool := utils.NewWorkerPool(1, 1)
+	defer pool.Close()
+	collection := newNsoneCollection("a", newTestSettings())
+	ctx := context.Background()
+	executed := make(chan string, 10)
+	for _, app := range []string{"a", "b", "c"} {
+		app := app
+		collection.Submit(ctx, pool, "pulsar", "/pulsar/apps", "", func() error {
+			for _, job := range []string{"1", "2"} {
+				job := job
+				collection.Submit(ctx, pool, "pulsar", "/pulsar/apps/{app}/jobs", "", func() error {
+					executed <- app + job
+					if app+job == "c2" {
+						return errors.New("expected")
+					}
+					return nil
+				})
+			}
+			return nil
+		})
+	}
+
+	errs := collection.Wait(ctx)
+	close(executed)
+
+	if len(executed) != 6 {
+		t.Errorf("Expected all 6 follow-up tasks to be executed but got %d.", len(executed))
+	}
+	if len(errs) != 1 || errs[0].endpoint != "/pulsar/apps/{app}/jobs" || errs[0].err.Error() != "expected" {
+		t.Errorf("Expected only the failed follow-up task but got %v.", errs)
+	}
+	if collection.successes["pulsar"] {
+		t.Errorf("Expected collector pulsar to be failed.")
+	}
+}
+
+func TestSetUsagePointExportsLastCompleteBucketByUsageGraphMode(t *testing.T) {
+	complete := &model.Usage{Queries: 5, Graph: [][]float64{{1500000000, 2}, {1500003600, 3}}}
+	incomplete := &model.Usage{Queries: 3, Graph: [][]float64{{1500003600, 3}}}
+	cases := []struct {
+		mode       model.UsageGraphMode
+		usage      *model.Usage
+		lastBucket bool
+		timestamp  bool
+	}{
+		{model.UG_OFF, complete, false, false},
+		{model.UG_OFF, incomplete, false, false},
+		{model.UG_LAST_BUCKET, complete, true, false},
+		{model.UG_LAST_BUCKET, incomplete, false, false},
+		{model.UG_TIMESTAMPS, complete, true, true},
+		{model.UG_TIMESTAMPS, incomplete, false, false},
+	}
+	for _, c := range cases {
+		settings := newTestSettings()
+		settings.UsageGraph = c.mode
+		collection := newNsoneCollection("a", settings)
+
+		if err := collection.setUsagePoint("usage_account_monthly", c.usage, "", "", model.RT_NONE); err != nil {
+			t.Fatal(err)
+		}
+
+		assertSamples(t, gather(t, collection.points["usage_account_monthly"]), map[string]float64{
+			`nsone_usage_account_monthly{account="a"}`: c.usage.Queries,
+		})
+		lastBucket, ok := collection.points["usage_account_monthly"+usageGraphSuffix]
+		if ok != c.mode.IsEnabled() {
+			t.Errorf("Expected a point of the usage graph for mode %s to be %v but got %v.", c.mode, c.mode.IsEnabled(), ok)
+		}
+		if ok {
+			samples := gather(t, lastBucket)
+			if c.lastBucket {
+				assertSamples(t, samples, map[string]float64{
+					`nsone_usage_account_monthly_last_bucket{account="a"}`: 2,
+				})
+			} else if len(samples) > 0 {
+				t.Errorf("Expected no last bucket for mode %s and graph %v but got %v.", c.mode, c.usage.Graph, samples)
+			}
+		}
+		if c.timestamp {
+			if len(collection.timestamps) != 1 {
+				t.Errorf("Expected one timestamp for mode %s but got %v.", c.mode, collection.timestamps)
+			}
+			for _, timestamp := range collection.timestamps {
+				if !timestamp.Equal(time.Unix(1500000000, 0)) {
+					t.Errorf("Expected the start of the last complete bucket as timestamp but got %v.", timestamp)
+				}
+			}
+		} else if len(collection.timestamps) > 0 {
+			t.Errorf("Expected no timestamps for mode %s and graph %v but got %v.", c.mode, c.usage.Graph, collection.timestamps)
+		}
+	}
+}
@@ -0,0 +1,492 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/echocat/nsone_exporter/model"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+// Configuration is the content of the file provided by -config.file.
+//
+// Example:
+//
+//	accounts:
+//	  - name: prod
+//	    token_file: /etc/nsone_exporter/prod.token
+//	    workers: 20
+//	    export:
+//	      usage:
+//	        periods: [1h, 30d]
+//	        zones:
+//	          exclude: ['^internal\.']
+//	      qps:
+//	        records:
+//	          include: ['^A www\.']
+type Configuration struct {
+	Accounts []*AccountConfiguration `yaml:"accounts"`
+}
+
+// AccountConfiguration describes one NSONE account. Exactly one of Token, TokenFile
+// and TokenEnv has to be provided. Every value that is not provided falls back to
+// the default of the corresponding -nsone.* flag.
+type AccountConfiguration struct {
+	Name                  string              `yaml:"name"`
+	Token                 string              `yaml:"token"`
+	TokenFile             string              `yaml:"token_file"`
+	TokenEnv              string              `yaml:"token_env"`
+	ApiUrl                string              `yaml:"api_url"`
+	CaFiles               []string            `yaml:"ca_files"`
+	Proxy                 string              `yaml:"proxy"`
+	Timeout               time.Duration       `yaml:"timeout"`
+	Workers               int                 `yaml:"workers"`
+	ConcurrentConnections int                 `yaml:"concurrent_connections"`
+	Export                ExportConfiguration `yaml:"export"`
+}
+
+// ExportConfiguration describes which metrics of an account are exported. Every
+// section that is not provided falls back to the default of the corresponding
+// -export.* flag.
+type ExportConfiguration struct {
+	Usage      UsageExportConfiguration      `yaml:"usage"`
+	Qps        QpsExportConfiguration        `yaml:"qps"`
+	Inventory  InventoryExportConfiguration  `yaml:"inventory"`
+	Drift      DriftExportConfiguration      `yaml:"drift"`
+	Pulsar     PulsarExportConfiguration     `yaml:"pulsar"`
+	Monitoring MonitoringExportConfiguration `yaml:"monitoring"`
+	DataFeeds  *FilterConfiguration          `yaml:"data_feeds"`
+}
+
+type UsageExportConfiguration struct {
+	// Periods of usages to export. Possible values: 1h, 24h and 30d
+	Periods []model.StatsPeriod `yaml:"periods"`
+	// Graph describes how the usage graph is exported. Possible values: off, last-bucket and timestamps
+	Graph   model.UsageGraphMode `yaml:"graph"`
+	Account *bool                `yaml:"account"`
+	Zones   *FilterConfiguration `yaml:"zones"`
+	Records *FilterConfiguration `yaml:"records"`
+}
+
+type QpsExportConfiguration struct {
+	Account *bool                `yaml:"account"`
+	Zones   *FilterConfiguration `yaml:"zones"`
+	Records *FilterConfiguration `yaml:"records"`
+}
+
+type InventoryExportConfiguration struct {
+	Zones   *FilterConfiguration `yaml:"zones"`
+	Records *FilterConfiguration `yaml:"records"`
+	// Answers matches '<recordType> <recordName>' of records which answer metadata and filter chain are exported.
+	Answers *FilterConfiguration `yaml:"answers"`
+	// LogZoneChanges logs the records that were added, removed or changed if the serial of a zone changes.
+	LogZoneChanges *bool `yaml:"log_zone_changes"`
+}
+
+type PulsarExportConfiguration struct {
+	// Jobs matches '<appName> <jobName>' of Pulsar jobs.
+	Jobs *FilterConfiguration `yaml:"jobs"`
+}
+
+type MonitoringExportConfiguration struct {
+	// Jobs matches the names of monitoring jobs.
+	Jobs *FilterConfiguration `yaml:"jobs"`
+	// Metrics exports the metrics (like rtt) of the matching jobs.
+	Metrics *bool `yaml:"metrics"`
+}
+
+type DriftExportConfiguration struct {
+	// Zones are the files (BIND zone files or YAML declarations) by zone which declare the expected records.
+	Zones map[string]string `yaml:"zones"`
+}
+
+// FilterConfiguration selects zones (matched against '<zoneName>') or records (matched
+// against '<recordType> <recordName>'). If Include is empty everything is included.
+type FilterConfiguration struct {
+	Enabled *bool    `yaml:"enabled"`
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+}
+
+func loadConfiguration(file string) (*Configuration, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read configuration %s. Cause: %v", file, err)
+	}
+	result := &Configuration{}
+	err = yaml.UnmarshalStrict(content, result)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse configuration %s. Cause: %v", file, err)
+	}
+	err = result.validate()
+	if err != nil {
+		return nil, fmt.Errorf("Illegal configuration %s. Cause: %v", file, err)
+	}
+	return result, nil
+}
+
+func (instance *Configuration) validate() error {
+	if len(instance.Accounts) <= 0 {
+		return fmt.Errorf("accounts: At least one account is required.")
+	}
+	names := map[string]bool{}
+	for i, account := range instance.Accounts {
+		path := fmt.Sprintf("accounts[%d]", i)
+		if account == nil {
+			return fmt.Errorf("%s: Empty account is not allowed.", path)
+		}
+		if len(strings.TrimSpace(account.Name)) <= 0 {
+			return fmt.Errorf("%s.name: Missing name.", path)
+		}
+		if names[account.Name] {
+			return fmt.Errorf("%s.name: Account '%s' was already defined.", path, account.Name)
+		}
+		names[account.Name] = true
+		if err := account.validate(); err != nil {
+			return fmt.Errorf("%s (%s).%v", path, account.Name, err)
+		}
+	}
+	return nil
+}
+
+func (instance *AccountConfiguration) validate() error {
+	tokenSources := 0
+	for _, source := range []string{instance.Token, instance.TokenFile, instance.TokenEnv} {
+		if len(source) > 0 {
+			tokenSources++
+		}
+	}
+	if tokenSources != 1 {
+		return fmt.Errorf("token: Exactly one of token, token_file and token_env is required.")
+	}
+	if len(instance.ApiUrl) > 0 {
+		if err := assertApiUrl(instance.ApiUrl); err != nil {
+			return fmt.Errorf("api_url: %v", err)
+		}
+	}
+	if instance.Timeout < 0 {
+		return fmt.Errorf("timeout: Could not be negative.")
+	}
+	if instance.Workers < 0 {
+		return fmt.Errorf("workers: Could not be negative.")
+	}
+	if instance.ConcurrentConnections < 0 {
+		return fmt.Errorf("concurrent_connections: Could not be negative.")
+	}
+	_, err := instance.Export.toSettings(NsoneExportSettings{})
+	if err != nil {
+		return fmt.Errorf("export.%v", err)
+	}
+	return nil
+}
+
+// resolveToken returns the token from the configured token source.
+func (instance *AccountConfiguration) resolveToken() (string, error) {
+	if len(instance.TokenFile) > 0 {
+		content, err := ioutil.ReadFile(instance.TokenFile)
+		if err != nil {
+			return "", fmt.Errorf("Could not read token of account %s. Cause: %v", instance.Name, err)
+		}
+		return strings.TrimSpace(string(content)), nil
+	}
+	if len(instance.TokenEnv) > 0 {
+		token := strings.TrimSpace(os.Getenv(instance.TokenEnv))
+		if len(token) <= 0 {
+			return "", fmt.Errorf("Environment variable %s for token of account %s is empty.", instance.TokenEnv, instance.Name)
+		}
+		return token, nil
+	}
+	return instance.Token, nil
+}
+
+// toSettings creates settings from this configuration. Everything not configured
+// is taken from the given defaults.
+func (instance ExportConfiguration) toSettings(defaults NsoneExportSettings) (NsoneExportSettings, error) {
+	result := defaults
+	var err error
+
+	if len(instance.Usage.Periods) > 0 {
+		result.UsageByHourFilter = model.NewDisabledFilter()
+		result.UsageByDayFilter = model.NewDisabledFilter()
+		result.UsageByMonthFilter = model.NewDisabledFilter()
+		for i, period := range instance.Usage.Periods {
+			switch period {
+			case model.P_HOURLY:
+				result.UsageByHourFilter, _ = model.NewFilter(nil, nil)
+			case model.P_DAILY:
+				result.UsageByDayFilter, _ = model.NewFilter(nil, nil)
+			case model.P_MONTHLY:
+				result.UsageByMonthFilter, _ = model.NewFilter(nil, nil)
+			default:
+				return result, fmt.Errorf("usage.periods[%d]: Illegal period: %s", i, period)
+			}
+		}
+	}
+	if instance.Usage.Graph != "" {
+		result.UsageGraph = instance.Usage.Graph
+	}
+	if instance.Usage.Account != nil {
+		result.UsageOfAccount = *instance.Usage.Account
+	}
+	if result.UsageOfZonesFilter, err = instance.Usage.Zones.toMatcher(result.UsageOfZonesFilter); err != nil {
+		return result, fmt.Errorf("usage.zones.%v", err)
+	}
+	if result.UsageOfRecordsFilter, err = instance.Usage.Records.toMatcher(result.UsageOfRecordsFilter); err != nil {
+		return result, fmt.Errorf("usage.records.%v", err)
+	}
+
+	if instance.Qps.Account != nil {
+		result.QpsOfAccount = *instance.Qps.Account
+	}
+	if result.QpsOfZonesFilter, err = instance.Qps.Zones.toMatcher(result.QpsOfZonesFilter); err != nil {
+		return result, fmt.Errorf("qps.zones.%v", err)
+	}
+	if result.QpsOfRecordsFilter, err = instance.Qps.Records.toMatcher(result.QpsOfRecordsFilter); err != nil {
+		return result, fmt.Errorf("qps.records.%v", err)
+	}
+
+	if result.InventoryOfZonesFilter, err = instance.Inventory.Zones.toMatcher(result.InventoryOfZonesFilter); err != nil {
+		return result, fmt.Errorf("inventory.zones.%v", err)
+	}
+	if result.InventoryOfRecordsFilter, err = instance.Inventory.Records.toMatcher(result.InventoryOfRecordsFilter); err != nil {
+		return result, fmt.Errorf("inventory.records.%v", err)
+	}
+	if result.InventoryOfAnswersFilter, err = instance.Inventory.Answers.toMatcher(result.InventoryOfAnswersFilter); err != nil {
+		return result, fmt.Errorf("inventory.answers.%v", err)
+	}
+	if instance.Inventory.LogZoneChanges != nil {
+		result.LogZoneChanges = *instance.Inventory.LogZoneChanges
+	}
+	if result.PulsarFilter, err = instance.Pulsar.Jobs.toMatcher(result.PulsarFilter); err != nil {
+		return result, fmt.Errorf("pulsar.jobs.%v", err)
+	}
+	if result.MonitorsFilter, err = instance.Monitoring.Jobs.toMatcher(result.MonitorsFilter); err != nil {
+		return result, fmt.Errorf("monitoring.jobs.%v", err)
+	}
+	if result.DataFeedsFilter, err = instance.DataFeeds.toMatcher(result.DataFeedsFilter); err != nil {
+		return result, fmt.Errorf("data_feeds.%v", err)
+	}
+	if instance.Monitoring.Metrics != nil {
+		result.MonitorMetrics = *instance.Monitoring.Metrics
+	}
+	if instance.Drift.Zones != nil {
+		result.DriftZoneFiles = instance.Drift.Zones
+	}
+	return result, nil
+}
+
+func (instance *FilterConfiguration) toMatcher(defaultMatcher model.Matcher) (model.Matcher, error) {
+	if instance == nil {
+		return defaultMatcher, nil
+	}
+	if instance.Enabled != nil && !*instance.Enabled {
+		return model.NewDisabledFilter(), nil
+	}
+	for i, include := range instance.Include {
+		if _, err := model.NewFilter([]string{include}, nil); err != nil {
+			return nil, fmt.Errorf("include[%d]: %v", i, err)
+		}
+	}
+	for i, exclude := range instance.Exclude {
+		if _, err := model.NewFilter(nil, []string{exclude}); err != nil {
+			return nil, fmt.Errorf("exclude[%d]: %v", i, err)
+		}
+	}
+	return model.NewFilter(instance.Include, instance.Exclude)
+}
+
+// createAccounts creates the accounts from -config.file and the -nsone.* flags. Values
+// of explicitly provided flags override the values of the configuration file.
+func createAccounts() ([]NsoneAccount, error) {
+	accountConfigurations := []*AccountConfiguration{}
+	if len(*configFile) > 0 {
+		configuration, err := loadConfiguration(*configFile)
+		if err != nil {
+			return nil, err
+		}
+		accountConfigurations = configuration.Accounts
+	}
+	for _, account := range nsoneAccounts.withDefault(*nsoneAccountName, *nsoneToken) {
+		accountConfigurations = withAccountToken(accountConfigurations, account)
+	}
+
+	explicitFlags := explicitlyProvidedFlags()
+	defaults := settingsFromFlags()
+	result := []NsoneAccount{}
+	for _, accountConfiguration := range accountConfigurations {
+		account, err := createAccount(accountConfiguration, defaults, explicitFlags)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, account)
+	}
+	return result, nil
+}
+
+func createAccount(configuration *AccountConfiguration, defaults NsoneExportSettings, explicitFlags map[string]bool) (NsoneAccount, error) {
+	token, err := configuration.resolveToken()
+	if err != nil {
+		return NsoneAccount{}, err
+	}
+	options := model.ClientOptions{
+		ApiUri:                               configuration.ApiUrl,
+		AccessToken:                          token,
+		Timeout:                              configuration.Timeout,
+		MaximumNumberOfConcurrentConnections: configuration.ConcurrentConnections,
+		CaFiles:                              configuration.CaFiles,
+		Proxy:                                configuration.Proxy,
+		Observer:                             newApiObserver(configuration.Name),
+	}
+	if len(options.ApiUri) <= 0 || explicitFlags["nsone.api-url"] {
+		options.ApiUri = *nsoneApiUrl
+	}
+	if options.Timeout <= 0 || explicitFlags["nsone.timeout"] {
+		options.Timeout = *nsoneTimeout
+	}
+	if options.MaximumNumberOfConcurrentConnections <= 0 || explicitFlags["nsone.number-of-concurrent-connections"] {
+		options.MaximumNumberOfConcurrentConnections = *nsoneNumberOfConcurrentConnections
+	}
+	if len(options.CaFiles) <= 0 || explicitFlags["nsone.ca-files"] {
+		options.CaFiles = splitList(*nsoneCaFiles)
+	}
+	if len(options.Proxy) <= 0 || explicitFlags["nsone.proxy"] {
+		options.Proxy = *nsoneProxy
+	}
+	numberOfWorkers := configuration.Workers
+	if numberOfWorkers <= 0 || explicitFlags["nsone.workers"] {
+		numberOfWorkers = *nsoneNumberOfWorkers
+	}
+
+	settings, err := configuration.Export.toSettings(defaults)
+	if err != nil {
+		return NsoneAccount{}, fmt.Errorf("Illegal export settings of account %s. Cause: %v", configuration.Name, err)
+	}
+	overrideSettingsByExplicitFlags(&settings, explicitFlags)
+
+	client, err := model.NewClient(options)
+	if err != nil {
+		return NsoneAccount{}, fmt.Errorf("Could not create client for account %s. Cause: %v", configuration.Name, err)
+	}
+	return NsoneAccount{
+		Name:            configuration.Name,
+		Client:          client,
+		NumberOfWorkers: numberOfWorkers,
+		Settings:        settings,
+	}, nil
+}
+
+func withAccountToken(configurations []*AccountConfiguration, account accountToken) []*AccountConfiguration {
+	for _, configuration := range configurations {
+		if configuration.Name == account.name {
+			configuration.Token = account.token
+			configuration.TokenFile = ""
+			configuration.TokenEnv = ""
+			return configurations
+		}
+	}
+	return append(configurations, &AccountConfiguration{
+		Name:  account.name,
+		Token: account.token,
+	})
+}
+
+func settingsFromFlags() NsoneExportSettings {
+	return NsoneExportSettings{
+		UsageByHourFilter:  exportUsageByHourFilter,
+		UsageByDayFilter:   exportUsageByDayFilter,
+		UsageByMonthFilter: exportUsageByMonthFilter,
+		UsageGraph:         exportUsageGraph,
+
+		UsageOfAccount:       *exportUsageOfAccount,
+		UsageOfZonesFilter:   exportUsageOfZonesFilter,
+		UsageOfRecordsFilter: exportUsageOfRecordsFilter,
+
+		QpsOfAccount:       *exportQpsOfAccount,
+		QpsOfZonesFilter:   exportQpsOfZonesFilter,
+		QpsOfRecordsFilter: exportQpsOfRecordsFilter,
+
+		InventoryOfZonesFilter:   exportInventoryOfZonesFilter,
+		InventoryOfRecordsFilter: exportInventoryOfRecordsFilter,
+		InventoryOfAnswersFilter: exportInventoryOfAnswersFilter,
+		LogZoneChanges:           *exportLogZoneChanges,
+
+		DriftZoneFiles: *exportDriftZoneFiles,
+		PulsarFilter:   exportPulsarFilter,
+		MonitorsFilter: exportMonitorsFilter,
+		MonitorMetrics: *exportMonitorMetrics,
+
+		DataFeedsFilter: exportDataFeedsFilter,
+	}
+}
+
+func overrideSettingsByExplicitFlags(settings *NsoneExportSettings, explicitFlags map[string]bool) {
+	fromFlags := settingsFromFlags()
+	if explicitFlags["export.usage-by-hour-filter"] {
+		settings.UsageByHourFilter = fromFlags.UsageByHourFilter
+	}
+	if explicitFlags["export.usage-by-day-filter"] {
+		settings.UsageByDayFilter = fromFlags.UsageByDayFilter
+	}
+	if explicitFlags["export.usage-by-month-filter"] {
+		settings.UsageByMonthFilter = fromFlags.UsageByMonthFilter
+	}
+	if explicitFlags["export.usage-graph"] {
+		settings.UsageGraph = fromFlags.UsageGraph
+	}
+	if explicitFlags["export.usage-of-account"] {
+		settings.UsageOfAccount = fromFlags.UsageOfAccount
+	}
+	if explicitFlags["export.usage-of-zones-filter"] {
+		settings.UsageOfZonesFilter = fromFlags.UsageOfZonesFilter
+	}
+	if explicitFlags["export.usage-of-records-filter"] {
+		settings.UsageOfRecordsFilter = fromFlags.UsageOfRecordsFilter
+	}
+	if explicitFlags["export.qps-of-account"] {
+		settings.QpsOfAccount = fromFlags.QpsOfAccount
+	}
+	if explicitFlags["export.qps-of-zones-filter"] {
+		settings.QpsOfZonesFilter = fromFlags.QpsOfZonesFilter
+	}
+	if explicitFlags["export.qps-of-records-filter"] {
+		settings.QpsOfRecordsFilter = fromFlags.QpsOfRecordsFilter
+	}
+	if explicitFlags["export.inventory-of-zones-filter"] {
+		settings.InventoryOfZonesFilter = fromFlags.InventoryOfZonesFilter
+	}
+	if explicitFlags["export.inventory-of-records-filter"] {
+		settings.InventoryOfRecordsFilter = fromFlags.InventoryOfRecordsFilter
+	}
+	if explicitFlags["export.inventory-of-answers-filter"] {
+		settings.InventoryOfAnswersFilter = fromFlags.InventoryOfAnswersFilter
+	}
+	if explicitFlags["export.log-zone-changes"] {
+		settings.LogZoneChanges = fromFlags.LogZoneChanges
+	}
+	if explicitFlags["export.drift-zone-file"] {
+		settings.DriftZoneFiles = fromFlags.DriftZoneFiles
+	}
+	if explicitFlags["export.pulsar-filter"] {
+		settings.PulsarFilter = fromFlags.PulsarFilter
+	}
+	if explicitFlags["export.monitors-filter"] {
+		settings.MonitorsFilter = fromFlags.MonitorsFilter
+	}
+	if explicitFlags["export.monitor-metrics"] {
+		settings.MonitorMetrics = fromFlags.MonitorMetrics
+	}
+	if explicitFlags["export.data-feeds-filter"] {
+		settings.DataFeedsFilter = fromFlags.DataFeedsFilter
+	}
+}
+
+func explicitlyProvidedFlags() map[string]bool {
+	result := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		result[f.Name] = true
+	})
+	return result
+}
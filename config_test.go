@@ -0,0 +1,113 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/echocat/nsone_exporter/model"
+)
+
+func loadTestConfiguration(t *testing.T, content string) (*Configuration, error) {
+	file, err := ioutil.TempFile("", "nsone_exporter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return loadConfiguration(file.Name())
+}
+
+func TestLoadConfiguration(t *testing.T) {
+	configuration, err := loadTestConfiguration(t, `accounts:
+  - name: prod
+    token: secret
+    workers: 20
+    export:
+      usage:
+        periods: [1h]
+      pulsar:
+        jobs:
+          include: ['^web ']
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(configuration.Accounts) != 1 || configuration.Accounts[0].Name != "prod" || configuration.Accounts[0].Workers != 20 {
+		t.Errorf("Expected account prod with 20 workers but got %+v.", configuration.Accounts)
+	}
+}
+
+func TestLoadConfigurationRejectsIllegalFiles(t *testing.T) {
+	cases := map[string]string{
+		"accounts:\n  - name: prod\n    token: secret\n    unknown: 1\n":                              "field unknown not found",
+		"accounts:\n  - name: prod\n    token: secret\n    export:\n      usage:\n        zone: {}\n": "field zone not found",
+		"accounts:\n  - name: prod\n    token: a\n  - name: prod\n    token: b\n":                     "accounts[1].name: Account 'prod' was already defined.",
+		"accounts:\n  - name: ' '\n    token: secret\n":                                               "accounts[0].name: Missing name.",
+		"accounts:\n  - token: secret\n":                                                              "accounts[0].name: Missing name.",
+		"accounts: []\n":                                                                              "accounts: At least one account is required.",
+		"accounts:\n  - name: prod\n    token: a\n    token_env: B\n":                                 "Exactly one of token, token_file and token_env is required.",
+		"accounts:\n  - name: prod\n    token: secret\n    api_url: api.nsone.net/v1\n":               "api_url: Scheme have to be either 'https' or 'http'",
+		"accounts:\n  - name: prod\n    token: secret\n    api_url: https://api.nsone.net/\n":         "api_url: Path have to point to the v1 API",
+	}
+	for content, expected := range cases {
+		_, err := loadTestConfiguration(t, content)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error containing '%s' for %q but got: %v", expected, content, err)
+		}
+	}
+}
+
+func TestCreateAccountPrefersExplicitFlagsOverConfiguration(t *testing.T) {
+	defer func(workers int) { *nsoneNumberOfWorkers = workers }(*nsoneNumberOfWorkers)
+	defer func(filter model.Regexp) { *exportPulsarFilter = filter }(*exportPulsarFilter)
+	*nsoneNumberOfWorkers = 7
+	if err := exportPulsarFilter.Set("^flag "); err != nil {
+		t.Fatal(err)
+	}
+	configuration := &AccountConfiguration{
+		Name:    "prod",
+		Token:   "secret",
+		Workers: 20,
+		Export: ExportConfiguration{
+			Pulsar: PulsarExportConfiguration{Jobs: &FilterConfiguration{Include: []string{"^file "}}},
+		},
+	}
+
+	account, err := createAccount(configuration, settingsFromFlags(), map[string]bool{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.NumberOfWorkers != 20 || !account.Settings.PulsarFilter.MatchString("file job") || account.Settings.PulsarFilter.MatchString("flag job") {
+		t.Errorf("Expected the values of the configuration without explicit flags but got %d workers and filter %v.", account.NumberOfWorkers, account.Settings.PulsarFilter)
+	}
+
+	account, err = createAccount(configuration, settingsFromFlags(), map[string]bool{"nsone.workers": true, "export.pulsar-filter": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.NumberOfWorkers != 7 || !account.Settings.PulsarFilter.MatchString("flag job") || account.Settings.PulsarFilter.MatchString("file job") {
+		t.Errorf("Expected the values of the explicit flags but got %d workers and filter %v.", account.NumberOfWorkers, account.Settings.PulsarFilter)
+	}
+}
+
+func TestExportConfigurationFallsBackToDefaults(t *testing.T) {
+	defaults := newTestSettings()
+	defaults.QpsOfZonesFilter = model.NewRegexpOrPanic("^example")
+
+	settings, err := ExportConfiguration{}.toSettings(defaults)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.QpsOfZonesFilter != defaults.QpsOfZonesFilter || settings.UsageOfAccount != defaults.UsageOfAccount {
+		t.Errorf("Expected the defaults but got %+v.", settings)
+	}
+}
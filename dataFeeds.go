@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"github.com/echocat/nsone_exporter/model"
+	"github.com/prometheus/client_golang/prometheus"
+	"strings"
+)
+
+// dataFeedPoints are the names of the points by metadata of data feeds that are exported.
+var dataFeedPoints = map[string]string{
+	"up":          "datafeed_up",
+	"weight":      "datafeed_weight",
+	"priority":    "datafeed_priority",
+	"connections": "datafeed_connections",
+	"loadavg":     "datafeed_loadavg",
+}
+
+// dataFeedReference is a destination of a data feed.
+type dataFeedReference struct {
+	source      *model.DataSource
+	feed        *model.DataFeed
+	destination *model.DataFeedDestination
+}
+
+func appendDataFeeds(to *map[string]*prometheus.GaugeVec) {
+	appendGaugeWith(to, "datafeed_info", "Information about data feeds. Always 1.", "source", "feed", "sourceType")
+	for metadata, name := range dataFeedPoints {
+		appendGaugeWith(to, name, "Value of the metadata '"+metadata+"' NSONE currently believes for data feeds.", "source", "feed")
+	}
+	appendGaugeWith(to, "datafeed_destination_info", "Records and answers data feeds are applied to. Always 1.", "source", "feed", "zone", "record", "recordType", "answer")
+}
+
+// exportDataFeedsIfRequired exports the metadata of every data feed that matches
+// '<sourceName> <feedName>' and joins the feeds to the records of the given (already
+// expanded) zones they are applied to.
+func (instance *nsoneAccountExporter) exportDataFeedsIfRequired(ctx context.Context, zones *model.Zones, target *nsoneCollection) {
+	if !instance.settings.DataFeedsFilter.HasValue() {
+		return
+	}
+	target.Submit(ctx, instance.workerPool, "datafeeds", "/data/sources", "", func() error {
+		sources, err := instance.client.GetDataSources(ctx)
+		if err != nil {
+			return err
+		}
+		for _, source := range *sources {
+			instance.exportDataFeedsOf(ctx, zones, source, target)
+		}
+		return nil
+	})
+}
+
+// exportDataFeedsOf submits the retrieval of the feeds of the given source.
+func (instance *nsoneAccountExporter) exportDataFeedsOf(ctx context.Context, zones *model.Zones, source *model.DataSource, target *nsoneCollection) {
+	target.Submit(ctx, instance.workerPool, "datafeeds", "/data/feeds/{source}", "", func() error {
+		feeds, err := instance.client.GetDataFeeds(ctx, source.Id)
+		if err != nil {
+			return err
+		}
+		references := map[string][]*dataFeedReference{}
+		for _, feed := range *feeds {
+			if !instance.settings.DataFeedsFilter.MatchString(source.Name + " " + feed.Name) {
+				continue
+			}
+			if err := exportDataFeed(source, feed, target); err != nil {
+				return err
+			}
+			for _, destination := range feed.Destinations {
+				record := destination.Record
+				if len(record) <= 0 && destination.Type == model.DD_RECORD {
+					record = destination.Id
+				}
+				references[record] = append(references[record], &dataFeedReference{
+					source:      source,
+					feed:        feed,
+					destination: destination,
+				})
+			}
+		}
+		instance.exportDataFeedDestinations(ctx, zones, references, target)
+		return nil
+	})
+}
+
+func exportDataFeed(source *model.DataSource, feed *model.DataFeed, target *nsoneCollection) error {
+	err := target.setPointWith("datafeed_info", 1, prometheus.Labels{
+		"source":     source.Name,
+		"feed":       feed.Name,
+		"sourceType": source.Type,
+	})
+	if err != nil {
+		return err
+	}
+	for metadata, name := range dataFeedPoints {
+		if value, ok := feed.NumericData(metadata); ok {
+			err := target.setPointWith(name, value, prometheus.Labels{
+				"source": source.Name,
+				"feed":   feed.Name,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// exportDataFeedDestinations exports the given references by id of record. The full
+// records are only retrieved if answers are referenced because only they contain the ids
+// of the answers.
+func (instance *nsoneAccountExporter) exportDataFeedDestinations(ctx context.Context, zones *model.Zones, references map[string][]*dataFeedReference, target *nsoneCollection) {
+	for _, zone := range *zones {
+		for _, record := range zone.Records {
+			zone, record := zone, record
+			referencesOfRecord := references[record.Id]
+			if len(record.Id) <= 0 || len(referencesOfRecord) <= 0 {
+				continue
+			}
+			answersRequired := false
+			for _, reference := range referencesOfRecord {
+				answersRequired = answersRequired || reference.destination.Type == model.DD_ANSWER
+			}
+			if !answersRequired {
+				if err := exportDataFeedDestinationsOf(zone, record, referencesOfRecord, target); err != nil {
+					target.failed("datafeeds", "/zones/{zone}", zone.Name, err)
+				}
+				continue
+			}
+			target.Submit(ctx, instance.workerPool, "datafeeds", "/zones/{zone}/{record}/{type}", zone.Name, func() error {
+				fullRecord, err := instance.client.GetRecord(ctx, zone.Name, record.Name, record.Type)
+				if err != nil {
+					return err
+				}
+				return exportDataFeedDestinationsOf(zone, fullRecord, referencesOfRecord, target)
+			})
+		}
+	}
+}
+
+func exportDataFeedDestinationsOf(zone *model.Zone, record *model.Record, references []*dataFeedReference, target *nsoneCollection) error {
+	answers := map[string]string{}
+	for _, answer := range record.Answers {
+		answers[answer.Id] = strings.Join(answer.Answer, " ")
+	}
+	for _, reference := range references {
+		answer := ""
+		if reference.destination.Type == model.DD_ANSWER {
+			answer = answers[reference.destination.Id]
+		}
+		err := target.setPointWith("datafeed_destination_info", 1, prometheus.Labels{
+			"source":     reference.source.Name,
+			"feed":       reference.feed.Name,
+			"zone":       zone.Name,
+			"record":     record.Name,
+			"recordType": record.Type.String(),
+			"answer":     answer,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/echocat/nsone_exporter/model"
+)
+
+func TestCollectExportsMatchingDataFeedsAndTheirDestinations(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	server.AddZone(&model.Zone{Name: "feed.net", TTL: 3600, Records: []*model.Record{
+		{Id: "r1", Name: "www.feed.net", Type: model.RT_A, ShortAnswers: []string{"1.1.1.1", "2.2.2.2"}, TTL: 60, Answers: []*model.Answer{
+			{Id: "a1", Answer: []string{"1.1.1.1"}},
+			{Id: "a2", Answer: []string{"2.2.2.2"}},
+		}},
+		{Id: "r2", Name: "api.feed.net", Type: model.RT_A, ShortAnswers: []string{"3.3.3.3"}, TTL: 60},
+	}})
+	server.AddDataSource(&model.DataSource{Id: "s1", Name: "mon", Type: "nsone_monitoring"})
+	server.AddDataFeed("s1", &model.DataFeed{Id: "f1", Name: "lga", Data: map[string]interface{}{"up": true, "weight": "5"}, Destinations: []*model.DataFeedDestination{
+		{Id: "a2", Type: model.DD_ANSWER, Record: "r1"},
+		{Id: "r2", Type: model.DD_RECORD},
+	}})
+	server.AddDataFeed("s1", &model.DataFeed{Id: "f2", Name: "sjc"})
+	settings := newTestSettings()
+	settings.DataFeedsFilter = model.NewRegexpOrPanic("^mon lga$")
+	exporter := NewNsoneExporter([]NsoneAccount{newTestAccount(t, server, "a", settings)})
+
+	samples := gather(t, exporter)
+
+	assertSamples(t, samples, map[string]float64{
+		`nsone_collector_success{account="a",collector="datafeeds"}`:                                                                                 1,
+		`nsone_datafeed_info{account="a",feed="lga",source="mon",sourceType="nsone_monitoring"}`:                                                     1,
+		`nsone_datafeed_up{account="a",feed="lga",source="mon"}`:                                                                                     1,
+		`nsone_datafeed_weight{account="a",feed="lga",source="mon"}`:                                                                                 5,
+		`nsone_datafeed_destination_info{account="a",answer="2.2.2.2",feed="lga",record="www.feed.net",recordType="A",source="mon",zone="feed.net"}`: 1,
+		`nsone_datafeed_destination_info{account="a",answer="",feed="lga",record="api.feed.net",recordType="A",source="mon",zone="feed.net"}`:        1,
+	})
+	refuteSamples(t, samples, `nsone_datafeed_info{account="a",feed="sjc"`)
+}
+
+func TestCollectKeepsDataFeedsOfOtherSourcesIfFeedsOfOneSourceCouldNotBeRetrieved(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	server.AddDataSource(&model.DataSource{Id: "s1", Name: "mon", Type: "nsone_monitoring"})
+	server.AddDataSource(&model.DataSource{Id: "s2", Name: "api", Type: "nsone_v1"})
+	server.AddDataFeed("s1", &model.DataFeed{Id: "f1", Name: "lga", Data: map[string]interface{}{"up": true}})
+	server.NotFound("/data/feeds/s2")
+	settings := newTestSettings()
+	settings.DataFeedsFilter = model.NewRegexpOrPanic(".*")
+	exporter := NewNsoneExporter([]NsoneAccount{newTestAccount(t, server, "a", settings)})
+
+	samples := gather(t, exporter)
+
+	assertSamples(t, samples, map[string]float64{
+		`nsone_collector_success{account="a",collector="datafeeds"}`:                             0,
+		`nsone_collect_errors_total{account="a",endpoint="/data/feeds/{source}",zone=""}`:        1,
+		`nsone_datafeed_info{account="a",feed="lga",source="mon",sourceType="nsone_monitoring"}`: 1,
+		`nsone_datafeed_up{account="a",feed="lga",source="mon"}`:                                 1,
+	})
+}
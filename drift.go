@@ -0,0 +1,549 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/echocat/nsone_exporter/model"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/yaml.v2"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	driftMissing = "missing"
+	driftExtra   = "extra"
+	driftChanged = "changed"
+)
+
+// zoneDrift are the differences between the records of a zone that are expected (declared in a
+// local file) and the records that are actually served by NSONE. SOA records are never compared
+// because their serial changes with every modification.
+type zoneDrift struct {
+	// Missing are the records that are expected but do not exist.
+	Missing []zoneDriftEntry
+	// Extra are the records that exist but are not expected.
+	Extra []zoneDriftEntry
+	// Changed are the records that exist but with other TTL or answers than expected.
+	Changed []zoneDriftEntry
+}
+
+// zoneDriftEntry is one record (name and type) with all its answers.
+type zoneDriftEntry struct {
+	Name     string
+	Type     model.RecordType
+	Expected string
+	Actual   string
+}
+
+// HasDrift returns true if there is any difference.
+func (instance zoneDrift) HasDrift() bool {
+	return len(instance.Missing) > 0 || len(instance.Extra) > 0 || len(instance.Changed) > 0
+}
+
+func (instance zoneDrift) writeTo(w io.Writer) error {
+	buffered := bufio.NewWriter(w)
+	for _, entry := range instance.Missing {
+		fmt.Fprintf(buffered, "- %s %s %s\n", entry.Name, entry.Type, entry.Expected)
+	}
+	for _, entry := range instance.Extra {
+		fmt.Fprintf(buffered, "+ %s %s %s\n", entry.Name, entry.Type, entry.Actual)
+	}
+	for _, entry := range instance.Changed {
+		fmt.Fprintf(buffered, "~ %s %s %s -> %s\n", entry.Name, entry.Type, entry.Expected, entry.Actual)
+	}
+	fmt.Fprintf(buffered, "%d missing, %d extra, %d changed\n", len(instance.Missing), len(instance.Extra), len(instance.Changed))
+	return buffered.Flush()
+}
+
+// diffZoneRecords compares the expected with the actual records.
+func diffZoneRecords(expected []zoneFileRecord, actual []zoneFileRecord) zoneDrift {
+	expectedStates := zoneRecordStatesOf(expected)
+	actualStates := zoneRecordStatesOf(actual)
+	result := zoneDrift{}
+	for _, key := range sortedKeysOf(expectedStates) {
+		name, recordType := splitZoneRecordKey(key)
+		actualState, ok := actualStates[key]
+		if !ok {
+			result.Missing = append(result.Missing, zoneDriftEntry{Name: name, Type: recordType, Expected: expectedStates[key]})
+		} else if actualState != expectedStates[key] {
+			result.Changed = append(result.Changed, zoneDriftEntry{Name: name, Type: recordType, Expected: expectedStates[key], Actual: actualState})
+		}
+	}
+	for _, key := range sortedKeysOf(actualStates) {
+		if _, ok := expectedStates[key]; !ok {
+			name, recordType := splitZoneRecordKey(key)
+			result.Extra = append(result.Extra, zoneDriftEntry{Name: name, Type: recordType, Actual: actualStates[key]})
+		}
+	}
+	return result
+}
+
+// zoneRecordStatesOf returns a comparable state (TTL and answers) by '<name> <type>' of the
+// given records. Names and data are compared case insensitive (except quoted character
+// strings) no matter if the records are parsed from a file or retrieved from NSONE. SOA
+// records are ignored.
+func zoneRecordStatesOf(records []zoneFileRecord) map[string]string {
+	ttls := map[string]int{}
+	answers := map[string][]string{}
+	for _, record := range records {
+		if record.Type == model.RT_SOA {
+			continue
+		}
+		key := strings.ToLower(fqdn(record.Name)) + " " + record.Type.String()
+		ttls[key] = record.TTL
+		answers[key] = append(answers[key], canonicalZoneFileDataOf(record.Data))
+	}
+	result := map[string]string{}
+	for key, data := range answers {
+		sort.Strings(data)
+		result[key] = "ttl=" + strconv.Itoa(ttls[key]) + " answers=[" + strings.Join(data, ", ") + "]"
+	}
+	return result
+}
+
+// canonicalZoneFileDataOf returns the given rdata in master file presentation in lower case.
+// Quoted character strings (like of TXT records) are kept as they are.
+func canonicalZoneFileDataOf(data string) string {
+	result := []byte(data)
+	quoted := false
+	for i := 0; i < len(result); i++ {
+		switch c := result[i]; {
+		case c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case !quoted && c >= 'A' && c <= 'Z':
+			result[i] = c + ('a' - 'A')
+		}
+	}
+	return string(result)
+}
+
+func splitZoneRecordKey(key string) (string, model.RecordType) {
+	parts := strings.SplitN(key, " ", 2)
+	return parts[0], model.RecordType(parts[1])
+}
+
+// diffZone compares the given zone of the given account with the records declared in file and
+// writes the differences to w. It returns true if there are any differences. The zone is
+// compared exactly like by the drift collector.
+func diffZone(ctx context.Context, account NsoneAccount, zoneName string, file string, w io.Writer) (bool, error) {
+	expected, err := loadZoneRecords(file, zoneName)
+	if err != nil {
+		return false, err
+	}
+	zone, err := account.Client.GetZone(ctx, zoneName)
+	if err != nil {
+		return false, fmt.Errorf("Could not retrieve zone %s of account %s. Cause: %v", zoneName, account.Name, err)
+	}
+	drift := diffZoneRecords(expected, actualZoneRecordsOf(zone))
+	if err := drift.writeTo(w); err != nil {
+		return false, err
+	}
+	return drift.HasDrift(), nil
+}
+
+func appendDrift(to *map[string]*prometheus.GaugeVec) {
+	appendGaugeWith(to, "zone_drift_records", "Number of records of zones that differ from the declaring zone file by kind (missing, extra, changed).", "zone", "kind")
+}
+
+// exportDriftIfRequired compares the given (already expanded) zones with the zone files of
+// DriftZoneFiles. The files are read on every collection to always compare with the latest
+// declaration.
+func (instance *nsoneAccountExporter) exportDriftIfRequired(zones *model.Zones, target *nsoneCollection) {
+	if len(instance.settings.DriftZoneFiles) <= 0 {
+		return
+	}
+	target.expect("drift")
+	zonesByName := map[string]*model.Zone{}
+	for _, zone := range *zones {
+		zonesByName[zone.Name] = zone
+	}
+	for zoneName, file := range instance.settings.DriftZoneFiles {
+		zone, ok := zonesByName[zoneName]
+		if !ok {
+			target.failed("drift", "/zones/{zone}", zoneName, fmt.Errorf("Zone does not exist in account %s.", instance.name))
+			continue
+		}
+		if err := exportDriftOf(zone, file, target); err != nil {
+			target.failed("drift", "/zones/{zone}", zoneName, err)
+		}
+	}
+}
+
+func exportDriftOf(zone *model.Zone, file string, target *nsoneCollection) error {
+	expected, err := loadZoneRecords(file, zone.Name)
+	if err != nil {
+		return err
+	}
+	drift := diffZoneRecords(expected, actualZoneRecordsOf(zone))
+	values := map[string]int{
+		driftMissing: len(drift.Missing),
+		driftExtra:   len(drift.Extra),
+		driftChanged: len(drift.Changed),
+	}
+	for kind, value := range values {
+		err := target.setPointWith("zone_drift_records", float64(value), prometheus.Labels{
+			"zone": zone.Name,
+			"kind": kind,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// actualZoneRecordsOf returns the records of the given zone details (with the short answers
+// of every record) how they are compared with the expected records. Linked records are
+// ignored because they are declared by the zone they are linked to.
+func actualZoneRecordsOf(zone *model.Zone) []zoneFileRecord {
+	result := []zoneFileRecord{}
+	for _, record := range zone.Records {
+		if len(record.Link) <= 0 {
+			result = append(result, zoneFileRecordsOf(record)...)
+		}
+	}
+	return result
+}
+
+// loadZoneRecords reads the records of the given zone from file. Files with the extension .yml
+// or .yaml are read as zoneDeclaration, every other file as RFC 1035 master file.
+func loadZoneRecords(file string, zoneName string) ([]zoneFileRecord, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("Could not open zone file %s. Cause: %v", file, err)
+	}
+	defer f.Close()
+	var result []zoneFileRecord
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yml", ".yaml":
+		result, err = parseZoneDeclaration(f, zoneName)
+	default:
+		result, err = parseZoneFile(f, zoneName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse zone file %s. Cause: %v", file, err)
+	}
+	return result, nil
+}
+
+// zoneDeclaration is the YAML alternative to master files.
+//
+// Example:
+//
+//	ttl: 3600
+//	records:
+//	  - name: www
+//	    type: A
+//	    ttl: 60
+//	    answers: [1.2.3.4, 1.2.3.5]
+//	  - name: '@'
+//	    type: MX
+//	    answers: ['10 mx1', [20, mx2.example.com.]]
+//
+// Names are relative to the zone if they do not end with a dot. Answers are either short
+// answers (like NSONE shows them) or lists of their elements.
+type zoneDeclaration struct {
+	TTL     int                      `yaml:"ttl"`
+	Records []*zoneRecordDeclaration `yaml:"records"`
+}
+
+type zoneRecordDeclaration struct {
+	Name    string                   `yaml:"name"`
+	Type    string                   `yaml:"type"`
+	TTL     int                      `yaml:"ttl"`
+	Answers []*zoneAnswerDeclaration `yaml:"answers"`
+}
+
+type zoneAnswerDeclaration struct {
+	shortAnswer string
+	elements    []string
+}
+
+func (instance *zoneAnswerDeclaration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&instance.elements); err == nil {
+		return nil
+	}
+	instance.elements = nil
+	return unmarshal(&instance.shortAnswer)
+}
+
+func parseZoneDeclaration(r io.Reader, zoneName string) ([]zoneFileRecord, error) {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	declaration := &zoneDeclaration{}
+	if err := yaml.UnmarshalStrict(content, declaration); err != nil {
+		return nil, err
+	}
+	origin := fqdn(strings.ToLower(zoneName))
+	result := []zoneFileRecord{}
+	for i, record := range declaration.Records {
+		if len(record.Name) <= 0 || len(record.Type) <= 0 {
+			return nil, fmt.Errorf("records[%d]: Name and type are required.", i)
+		}
+		recordType := model.RecordType(strings.ToUpper(record.Type))
+		ttl := record.TTL
+		if ttl <= 0 {
+			ttl = declaration.TTL
+		}
+		if ttl <= 0 {
+			return nil, fmt.Errorf("records[%d]: Neither a ttl of the record nor of the zone provided.", i)
+		}
+		for _, answer := range record.Answers {
+			elements := answer.elements
+			if elements == nil {
+				elements = elementsOfShortAnswer(recordType, answer.shortAnswer)
+			}
+			result = append(result, newParsedZoneFileRecord(absoluteNameOf(record.Name, origin), ttl, recordType, elements, origin))
+		}
+	}
+	return result, nil
+}
+
+// parseZoneFile parses the records of an RFC 1035 master file. $INCLUDE and $GENERATE are not
+// supported.
+func parseZoneFile(r io.Reader, zoneName string) ([]zoneFileRecord, error) {
+	parser := &zoneFileParser{
+		origin:   fqdn(strings.ToLower(zoneName)),
+		ttl:      -1,
+		lastName: fqdn(strings.ToLower(zoneName)),
+		lastTTL:  -1,
+	}
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		if err := parser.parseLine(scanner.Text()); err != nil {
+			return nil, fmt.Errorf("Line %d: %v", lineNumber, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if parser.depth > 0 {
+		return nil, fmt.Errorf("Line %d: Unclosed parenthesis.", lineNumber)
+	}
+	return parser.records, nil
+}
+
+type zoneFileParser struct {
+	origin     string
+	ttl        int
+	lastName   string
+	lastTTL    int
+	records    []zoneFileRecord
+	depth      int
+	pending    []string
+	continuing bool
+}
+
+func (instance *zoneFileParser) parseLine(line string) error {
+	if instance.depth <= 0 {
+		instance.continuing = len(line) > 0 && (line[0] == ' ' || line[0] == '\t')
+	}
+	tokens, depth, err := tokenizeZoneFileLine(line, instance.depth)
+	if err != nil {
+		return err
+	}
+	instance.depth = depth
+	instance.pending = append(instance.pending, tokens...)
+	if instance.depth > 0 || len(instance.pending) <= 0 {
+		return nil
+	}
+	tokens = instance.pending
+	instance.pending = nil
+	return instance.parseEntry(tokens)
+}
+
+func (instance *zoneFileParser) parseEntry(tokens []string) error {
+	switch strings.ToUpper(tokens[0]) {
+	case "$ORIGIN":
+		if len(tokens) != 2 {
+			return fmt.Errorf("Expected '$ORIGIN <name>'.")
+		}
+		instance.origin = absoluteNameOf(tokens[1], instance.origin)
+		return nil
+	case "$TTL":
+		if len(tokens) != 2 {
+			return fmt.Errorf("Expected '$TTL <ttl>'.")
+		}
+		ttl, err := parseZoneFileTTL(tokens[1])
+		if err != nil {
+			return err
+		}
+		instance.ttl = ttl
+		return nil
+	case "$INCLUDE", "$GENERATE":
+		return fmt.Errorf("%s is not supported.", tokens[0])
+	}
+	name := instance.lastName
+	if !instance.continuing {
+		name = absoluteNameOf(tokens[0], instance.origin)
+		tokens = tokens[1:]
+	}
+	ttl := -1
+	for i := 0; i < 2 && len(tokens) > 0; i++ {
+		if candidate, err := parseZoneFileTTL(tokens[0]); err == nil && ttl < 0 {
+			ttl = candidate
+		} else if upper := strings.ToUpper(tokens[0]); upper == "IN" || upper == "CH" || upper == "HS" || upper == "CS" {
+			if upper != "IN" {
+				return fmt.Errorf("Only class IN is supported but got: %s", tokens[0])
+			}
+		} else {
+			break
+		}
+		tokens = tokens[1:]
+	}
+	if len(tokens) <= 0 {
+		return fmt.Errorf("Missing type of record %s.", name)
+	}
+	if ttl < 0 {
+		ttl = instance.ttl
+	}
+	if ttl < 0 {
+		ttl = instance.lastTTL
+	}
+	if ttl < 0 {
+		return fmt.Errorf("Missing TTL of record %s: Neither the record, a previous record nor $TTL provides one.", name)
+	}
+	recordType := model.RecordType(strings.ToUpper(tokens[0]))
+	elements := tokens[1:]
+	if recordType == model.RT_TXT || recordType == model.RT_SPF {
+		elements = []string{strings.Join(elements, "")}
+	}
+	instance.records = append(instance.records, newParsedZoneFileRecord(name, ttl, recordType, elements, instance.origin))
+	instance.lastName = name
+	instance.lastTTL = ttl
+	return nil
+}
+
+// tokenizeZoneFileLine splits the given line into its tokens. Quoted tokens are returned
+// without quotes and escapes. depth is the number of open parentheses before and after
+// the line.
+func tokenizeZoneFileLine(line string, depth int) ([]string, int, error) {
+	tokens := []string{}
+	current := &bytes.Buffer{}
+	inToken, quoted := false, false
+	finishToken := func() {
+		if inToken {
+			tokens = append(tokens, current.String())
+			current.Reset()
+			inToken = false
+		}
+	}
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\\' && i+1 < len(line):
+			i++
+			if i+2 < len(line) && isDigit(line[i]) && isDigit(line[i+1]) && isDigit(line[i+2]) {
+				value, _ := strconv.Atoi(line[i : i+3])
+				current.WriteByte(byte(value))
+				i += 2
+			} else {
+				current.WriteByte(line[i])
+			}
+			inToken = true
+		case quoted && c == '"':
+			quoted = false
+		case quoted:
+			current.WriteByte(c)
+		case c == '"':
+			quoted, inToken = true, true
+		case c == ';':
+			finishToken()
+			return tokens, depth, nil
+		case c == '(':
+			finishToken()
+			depth++
+		case c == ')':
+			finishToken()
+			if depth <= 0 {
+				return nil, depth, fmt.Errorf("Unexpected ')'.")
+			}
+			depth--
+		case c == ' ' || c == '\t' || c == '\r':
+			finishToken()
+		default:
+			current.WriteByte(c)
+			inToken = true
+		}
+	}
+	if quoted {
+		return nil, depth, fmt.Errorf("Unclosed quote.")
+	}
+	finishToken()
+	return tokens, depth, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// parseZoneFileTTL parses a TTL in seconds or with units (like 1h30m).
+func parseZoneFileTTL(value string) (int, error) {
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return seconds, nil
+	}
+	units := map[byte]int{'s': 1, 'm': 60, 'h': 3600, 'd': 86400, 'w': 604800}
+	result, number := 0, ""
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if isDigit(c) {
+			number += string(c)
+			continue
+		}
+		unit, ok := units[c|0x20]
+		if !ok || len(number) <= 0 {
+			return 0, fmt.Errorf("Illegal TTL: %s", value)
+		}
+		n, _ := strconv.Atoi(number)
+		result += n * unit
+		number = ""
+	}
+	if len(number) > 0 {
+		return 0, fmt.Errorf("Illegal TTL: %s", value)
+	}
+	return result, nil
+}
+
+func newParsedZoneFileRecord(name string, ttl int, recordType model.RecordType, elements []string, origin string) zoneFileRecord {
+	elements = append([]string{}, elements...)
+	for _, i := range zoneFileNameElements[recordType] {
+		if i < len(elements) {
+			elements[i] = absoluteNameOf(elements[i], origin)
+		}
+	}
+	return zoneFileRecord{
+		Name: name,
+		TTL:  ttl,
+		Type: recordType,
+		Data: zoneFileDataOf(recordType, elements),
+	}
+}
+
+// absoluteNameOf returns the lower case fully qualified name of the given name relative to origin.
+func absoluteNameOf(name string, origin string) string {
+	if name == "@" {
+		return origin
+	}
+	name = strings.ToLower(name)
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	if origin == "." {
+		return name + "."
+	}
+	return name + "." + origin
+}
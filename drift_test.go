@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/echocat/nsone_exporter/model"
+)
+
+func writeTestZoneFile(t *testing.T, name string, content string) (file string, cleanup func()) {
+	directory, err := ioutil.TempDir("", "nsone_exporter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file = filepath.Join(directory, name)
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		os.RemoveAll(directory)
+		t.Fatal(err)
+	}
+	return file, func() { os.RemoveAll(directory) }
+}
+
+func TestParseZoneFile(t *testing.T) {
+	content := `; Zone of example.com
+$TTL 1h
+@	IN	SOA	ns1 hostmaster (
+		2018010101 ; serial
+		7200       ; refresh
+		3600 1209600 300 )
+	IN	NS	ns1.example.net.
+www	60	IN	A	1.2.3.4
+	IN	60	A	1.2.3.5
+WWW.Example.COM.	60	IN	AAAA	2001:db8::1
+@	MX	10	mx
+txt	TXT	"v=spf1 include:example.net" " -all" ; comment
+quoted	TXT	"say \"hello\"; bye" "\065\\"
+$ORIGIN sub.example.com.
+host	300	CNAME	www.example.com.
+relative	300	CNAME	host
+`
+
+	records, err := parseZoneFile(strings.NewReader(content), "Example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []zoneFileRecord{
+		{Name: "example.com.", TTL: 3600, Type: model.RT_SOA, Data: "ns1 hostmaster 2018010101 7200 3600 1209600 300"},
+		{Name: "example.com.", TTL: 3600, Type: model.RT_NS, Data: "ns1.example.net."},
+		{Name: "www.example.com.", TTL: 60, Type: model.RT_A, Data: "1.2.3.4"},
+		{Name: "www.example.com.", TTL: 60, Type: model.RT_A, Data: "1.2.3.5"},
+		{Name: "www.example.com.", TTL: 60, Type: model.RT_AAAA, Data: "2001:db8::1"},
+		{Name: "example.com.", TTL: 3600, Type: model.RT_MX, Data: "10 mx.example.com."},
+		{Name: "txt.example.com.", TTL: 3600, Type: model.RT_TXT, Data: `"v=spf1 include:example.net -all"`},
+		{Name: "quoted.example.com.", TTL: 3600, Type: model.RT_TXT, Data: `"say \"hello\"; byeA\\"`},
+		{Name: "host.sub.example.com.", TTL: 300, Type: model.RT_CNAME, Data: "www.example.com."},
+		{Name: "relative.sub.example.com.", TTL: 300, Type: model.RT_CNAME, Data: "host.sub.example.com."},
+	}
+	if !reflect.DeepEqual(records, expected) {
+		t.Errorf("Expected:\n%v\nbut got:\n%v", expected, records)
+	}
+}
+
+func TestParseZoneFileUsesTTLOfPreviousRecordWithoutTTLDirective(t *testing.T) {
+	records, err := parseZoneFile(strings.NewReader("www 60 A 1.2.3.4\napi A 1.2.3.5\n"), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[1].TTL != 60 {
+		t.Errorf("Expected the TTL of the previous record but got: %v", records)
+	}
+}
+
+func TestParseZoneFileRejectsIllegalFiles(t *testing.T) {
+	cases := map[string]string{
+		"www A 1.2.3.4\n":                     "Missing TTL of record www.example.com.",
+		"$TTL 60\nwww CH A 1.2.3.4\n":         "Only class IN is supported",
+		"$TTL 60\n@ SOA ns1 hostmaster ( 1\n": "Unclosed parenthesis.",
+		"$TTL 60\n@ A 1.2.3.4 )\n":            "Unexpected ')'.",
+		"$TTL 60\ntxt TXT \"open\n":           "Unclosed quote.",
+		"$INCLUDE other.zone\n":               "$INCLUDE is not supported.",
+		"$TTL 1x\n":                           "Illegal TTL: 1x",
+	}
+	for content, expected := range cases {
+		_, err := parseZoneFile(strings.NewReader(content), "example.com")
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error containing '%s' for %q but got: %v", expected, content, err)
+		}
+	}
+}
+
+func TestParseZoneDeclaration(t *testing.T) {
+	content := `ttl: 3600
+records:
+  - name: www
+    type: a
+    ttl: 60
+    answers: [1.2.3.4]
+  - name: '@'
+    type: MX
+    answers: ['10 mx', [20, mx2.example.net.]]
+`
+
+	records, err := parseZoneDeclaration(strings.NewReader(content), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []zoneFileRecord{
+		{Name: "www.example.com.", TTL: 60, Type: model.RT_A, Data: "1.2.3.4"},
+		{Name: "example.com.", TTL: 3600, Type: model.RT_MX, Data: "10 mx.example.com."},
+		{Name: "example.com.", TTL: 3600, Type: model.RT_MX, Data: "20 mx2.example.net."},
+	}
+	if !reflect.DeepEqual(records, expected) {
+		t.Errorf("Expected:\n%v\nbut got:\n%v", expected, records)
+	}
+	if _, err := parseZoneDeclaration(strings.NewReader("records: [{name: www, type: A, answers: [1.2.3.4]}]"), "example.com"); err == nil {
+		t.Errorf("Expected an error for a record without any TTL.")
+	}
+}
+
+func TestDiffZoneRecordsWritesDifferences(t *testing.T) {
+	expected := []zoneFileRecord{
+		{Name: "example.com.", TTL: 3600, Type: model.RT_SOA, Data: "ns1 hostmaster 1 2 3 4 5"},
+		{Name: "www.example.com.", TTL: 300, Type: model.RT_A, Data: "1.2.3.4"},
+		{Name: "api.example.com.", TTL: 60, Type: model.RT_A, Data: "5.6.7.8"},
+		{Name: "mail.example.com.", TTL: 60, Type: model.RT_AAAA, Data: "2001:db8::1"},
+		{Name: "example.com.", TTL: 60, Type: model.RT_MX, Data: "10 mx.example.com."},
+		{Name: "example.com.", TTL: 60, Type: model.RT_TXT, Data: `"Hello"`},
+	}
+	actual := []zoneFileRecord{
+		{Name: "example.com.", TTL: 3600, Type: model.RT_SOA, Data: "dns1.p01.nsone.net. hostmaster.nsone.net. 7 2 3 4 5"},
+		{Name: "www.example.com.", TTL: 60, Type: model.RT_A, Data: "1.2.3.4"},
+		{Name: "Mail.Example.com.", TTL: 60, Type: model.RT_AAAA, Data: "2001:DB8::1"},
+		{Name: "example.com.", TTL: 60, Type: model.RT_MX, Data: "10 MX.Example.COM."},
+		{Name: "example.com.", TTL: 60, Type: model.RT_TXT, Data: `"hello"`},
+		{Name: "example.com.", TTL: 60, Type: model.RT_NS, Data: "dns1.p01.nsone.net."},
+	}
+
+	drift := diffZoneRecords(expected, actual)
+	buffer := &bytes.Buffer{}
+	if err := drift.writeTo(buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedOutput := "- api.example.com. A ttl=60 answers=[5.6.7.8]\n" +
+		"+ example.com. NS ttl=60 answers=[dns1.p01.nsone.net.]\n" +
+		"~ example.com. TXT ttl=60 answers=[\"Hello\"] -> ttl=60 answers=[\"hello\"]\n" +
+		"~ www.example.com. A ttl=300 answers=[1.2.3.4] -> ttl=60 answers=[1.2.3.4]\n" +
+		"1 missing, 1 extra, 2 changed\n"
+	if actual := buffer.String(); actual != expectedOutput {
+		t.Errorf("Expected:\n%s\nbut got:\n%s", expectedOutput, actual)
+	}
+	if !drift.HasDrift() {
+		t.Errorf("Expected a drift.")
+	}
+	if drift := diffZoneRecords(expected, expected); drift.HasDrift() {
+		t.Errorf("Expected no drift of equal records but got: %+v", drift)
+	}
+}
+
+const testDriftZoneFile = `$ORIGIN example.com.
+$TTL 3600
+@	SOA	ns1 hostmaster ( 1 2 3 4 5 )
+www	300	A	1.2.3.4
+api	60	A	5.6.7.8
+`
+
+func TestCollectAndDiffCommandReportTheSameDrift(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	file, cleanup := writeTestZoneFile(t, "example.com.zone", testDriftZoneFile)
+	defer cleanup()
+	settings := newTestSettings()
+	settings.DriftZoneFiles = map[string]string{"example.com": file}
+	account := newTestAccount(t, server, "a", settings)
+	exporter := NewNsoneExporter([]NsoneAccount{account})
+
+	samples := gather(t, exporter)
+	output := &bytes.Buffer{}
+	drift, err := diffZone(context.Background(), account, "example.com", file, output)
+
+	assertSamples(t, samples, map[string]float64{
+		`nsone_collector_success{account="a",collector="drift"}`:                  1,
+		`nsone_zone_drift_records{account="a",kind="missing",zone="example.com"}`: 1,
+		`nsone_zone_drift_records{account="a",kind="extra",zone="example.com"}`:   1,
+		`nsone_zone_drift_records{account="a",kind="changed",zone="example.com"}`: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !drift {
+		t.Errorf("Expected a drift.")
+	}
+	expectedOutput := "- api.example.com. A ttl=60 answers=[5.6.7.8]\n" +
+		"+ example.com. MX ttl=60 answers=[10 mx.example.com.]\n" +
+		"~ www.example.com. A ttl=300 answers=[1.2.3.4] -> ttl=60 answers=[1.2.3.4]\n" +
+		"1 missing, 1 extra, 1 changed\n"
+	if actual := output.String(); actual != expectedOutput {
+		t.Errorf("Expected:\n%s\nbut got:\n%s", expectedOutput, actual)
+	}
+}
+
+func TestCollectMarksDriftAsFailedIfZoneFileIsIllegal(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	file, cleanup := writeTestZoneFile(t, "example.com.zone", "www A 1.2.3.4\n")
+	defer cleanup()
+	settings := newTestSettings()
+	settings.DriftZoneFiles = map[string]string{"example.com": file}
+	exporter := NewNsoneExporter([]NsoneAccount{newTestAccount(t, server, "a", settings)})
+
+	samples := gather(t, exporter)
+
+	assertSamples(t, samples, map[string]float64{
+		`nsone_collector_success{account="a",collector="drift"}`:                              0,
+		`nsone_collect_errors_total{account="a",endpoint="/zones/{zone}",zone="example.com"}`: 1,
+	})
+	refuteSamples(t, samples, "nsone_zone_drift_records")
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// dump runs exactly one collection of the given exporter and writes the result in the
+// text exposition format to output (stdout if empty or '-'). A file is replaced atomically
+// so it could be consumed by the textfile collector of node_exporter while it is written.
+// Timestamps of samples (see -export.usage-graph=timestamps) are only written to stdout
+// because the textfile collector of node_exporter rejects files that contain timestamps.
+// An error is returned if the collection of any account failed; the metrics are written anyway.
+func dump(exporter *NsoneExporter, output string) error {
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(exporter); err != nil {
+		return err
+	}
+	families, err := registry.Gather()
+	if err != nil {
+		return fmt.Errorf("Could not gather metrics. Cause: %v", err)
+	}
+
+	write := func(w io.Writer) error {
+		buffered := bufio.NewWriter(w)
+		for _, family := range families {
+			if _, err := expfmt.MetricFamilyToText(buffered, family); err != nil {
+				return err
+			}
+		}
+		return buffered.Flush()
+	}
+	if len(output) <= 0 || output == "-" {
+		err = write(os.Stdout)
+	} else {
+		stripTimestampsOf(families)
+		err = writeFileAtomically(output, write)
+	}
+	if err != nil {
+		return fmt.Errorf("Could not write metrics to %s. Cause: %v", output, err)
+	}
+
+	for _, account := range exporter.currentAccounts() {
+		if snapshot := account.currentSnapshot(); snapshot == nil || snapshot.up <= 0 {
+			return fmt.Errorf("Collecting account %s failed.", account.name)
+		}
+	}
+	return nil
+}
+
+// writeFileAtomically writes into a temporary file next to the given file and renames it
+// to the given file if write was successful.
+func writeFileAtomically(file string, write func(w io.Writer) error) error {
+	f, err := ioutil.TempFile(filepath.Dir(file), "."+filepath.Base(file)+".")
+	if err != nil {
+		return err
+	}
+	success := false
+	defer func() {
+		if !success {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+	if err := write(f); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), file); err != nil {
+		return err
+	}
+	success = true
+	return nil
+}
+
+func stripTimestampsOf(families []*dto.MetricFamily) {
+	for _, family := range families {
+		for _, metric := range family.Metric {
+			metric.TimestampMs = nil
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/echocat/nsone_exporter/model"
+)
+
+func TestDumpWritesFileWithoutTimestamps(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	settings := newTestSettings()
+	settings.UsageGraph = model.UG_TIMESTAMPS
+	exporter := NewNsoneExporter([]NsoneAccount{newTestAccount(t, server, "a", settings)})
+	directory, err := ioutil.TempDir("", "nsone_exporter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+	output := filepath.Join(directory, "nsone.prom")
+
+	if err := dump(exporter, output); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lastBucketFound := false
+	for _, line := range strings.Split(string(content), "\n") {
+		if len(line) <= 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if fields := strings.Fields(line); len(fields) != 2 {
+			t.Errorf("Expected sample without timestamp but got: %s", line)
+		}
+		if strings.HasPrefix(line, "nsone_usage_account_monthly_last_bucket{") {
+			lastBucketFound = true
+		}
+	}
+	if !lastBucketFound {
+		t.Errorf("Expected the last bucket of the usage graph in:\n%s", content)
+	}
+}
@@ -1,43 +1,211 @@
 package main
 
 import (
-	"fmt"
+	"context"
 	"github.com/echocat/nsone_exporter/model"
-	"github.com/echocat/nsone_exporter/utils"
 	"github.com/prometheus/client_golang/prometheus"
 	"strings"
 	"sync"
 	"time"
-	"github.com/prometheus/common/log"
 )
 
 type NsoneExportSettings struct {
-	UsageByHourFilter    *model.Regexp
-	UsageByDayFilter     *model.Regexp
-	UsageByMonthFilter   *model.Regexp
+	UsageByHourFilter    model.Matcher
+	UsageByDayFilter     model.Matcher
+	UsageByMonthFilter   model.Matcher
+	UsageGraph           model.UsageGraphMode
 
 	UsageOfAccount       bool
-	UsageOfZonesFilter   *model.Regexp
-	UsageOfRecordsFilter *model.Regexp
+	UsageOfZonesFilter   model.Matcher
+	UsageOfRecordsFilter model.Matcher
 
 	QpsOfAccount         bool
-	QpsOfZonesFilter     *model.Regexp
-	QpsOfRecordsFilter   *model.Regexp
+	QpsOfZonesFilter     model.Matcher
+	QpsOfRecordsFilter   model.Matcher
+
+	InventoryOfZonesFilter   model.Matcher
+	InventoryOfRecordsFilter model.Matcher
+	// InventoryOfAnswersFilter matches '<recordType> <recordName>' of records which answer
+	// metadata and filter chain should be exported.
+	InventoryOfAnswersFilter model.Matcher
+	// LogZoneChanges logs the records that were added, removed or changed if the serial of a zone changes.
+	LogZoneChanges bool
+	// DriftZoneFiles are the files (by zone) which declare the expected records of zones.
+	DriftZoneFiles map[string]string
+	// PulsarFilter matches '<appName> <jobName>' of Pulsar jobs to export.
+	PulsarFilter model.Matcher
+	// MonitorsFilter matches the names of monitoring jobs to export.
+	MonitorsFilter model.Matcher
+	// MonitorMetrics exports the metrics (like rtt) of every job of MonitorsFilter.
+	MonitorMetrics bool
+	// DataFeedsFilter matches '<sourceName> <feedName>' of data feeds to export.
+	DataFeedsFilter model.Matcher
 }
 
 type NsoneExporter struct {
-	client         *model.Client
-	settings       NsoneExportSettings
-	workerPool     *utils.WorkerPool
-	collectionLock sync.RWMutex
-	pointsLock     sync.RWMutex
+	accounts     []*nsoneAccountExporter
+	accountsLock sync.RWMutex
+
+	collectionInterval time.Duration
+	stop               context.CancelFunc
+	trigger            chan struct{}
+
+	up                      *prometheus.Desc
+	lastCollectionTimestamp *prometheus.Desc
+	collectorSuccess        *prometheus.Desc
+	collectErrors           *prometheus.CounterVec
+	zoneChanges             *prometheus.CounterVec
+	monitorStatusChanges    *prometheus.CounterVec
+	rateLimitRemaining      *prometheus.Desc
+	throttledRequests       *prometheus.Desc
+	rateLimitedRequests     *prometheus.Desc
+	apiRequestsInFlight     *prometheus.Desc
+}
+
+func NewNsoneExporter(accounts []NsoneAccount) *NsoneExporter {
+	result := &NsoneExporter{
+		up: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "up"),
+			"Was the NSONE instance query successful?",
+			[]string{"account"}, nil,
+		),
+		lastCollectionTimestamp: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "last_collection_timestamp_seconds"),
+			"Unix timestamp of the end of the last collection of the exported stats.",
+			[]string{"account"}, nil,
+		),
+		collectorSuccess: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "collector_success"),
+			"Were all requests of the collector successful in the last collection?",
+			[]string{"account", "collector"}, nil,
+		),
+		collectErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "collect_errors_total",
+			Help:      "Number of failed requests against NSONE while collecting.",
+		}, []string{"account", "endpoint", "zone"}),
+		zoneChanges: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "zone_changes_total",
+			Help:      "Number of detected changes of the serial of zones since start of the exporter.",
+		}, []string{"account", "zone"}),
+		monitorStatusChanges: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "monitor_status_changes_total",
+			Help:      "Number of detected changes of the status of monitoring jobs by region since start of the exporter.",
+		}, []string{"account", "job", "region"}),
+		rateLimitRemaining: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "api", "ratelimit_remaining"),
+			"Estimated number of requests against NSONE that could be executed before the rate limit is reached.",
+			[]string{"account"}, nil,
+		),
+		throttledRequests: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "api", "throttled_requests_total"),
+			"Number of requests against NSONE that were delayed to not exceed the rate limit.",
+			[]string{"account"}, nil,
+		),
+		rateLimitedRequests: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "api", "rate_limited_requests_total"),
+			"Number of requests against NSONE that were rejected because the rate limit was exceeded.",
+			[]string{"account"}, nil,
+		),
+		apiRequestsInFlight: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "api", "requests_in_flight"),
+			"Number of requests against NSONE that are currently in flight.",
+			[]string{"account"}, nil,
+		),
+	}
+	for _, account := range accounts {
+		result.accounts = append(result.accounts, newNsoneAccountExporter(account, result.collectErrors, result.zoneChanges, result.monitorStatusChanges))
+	}
+	return result
+}
+
+// Reload replaces all accounts atomically. Scrapes that are currently running are finished
+// with the previous accounts. Accounts that did not change are kept as they are. The latest
+// snapshot of a changed account is kept until its next collection if an account with the
+// same name still exists. Replaced accounts are closed after their current collection. The
+// api metrics of accounts that do not exist anymore are deleted.
+func (instance *NsoneExporter) Reload(accounts []NsoneAccount) {
+	instance.accountsLock.Lock()
+	oldAccounts := map[string]*nsoneAccountExporter{}
+	for _, account := range instance.accounts {
+		oldAccounts[account.name] = account
+	}
+	newAccounts := []*nsoneAccountExporter{}
+	namesOfNewAccounts := map[string]bool{}
+	for _, account := range accounts {
+		namesOfNewAccounts[account.Name] = true
+		oldAccount, ok := oldAccounts[account.Name]
+		if ok && oldAccount.account.equals(account) {
+			delete(oldAccounts, account.Name)
+			newAccounts = append(newAccounts, oldAccount)
+			continue
+		}
+		newAccount := newNsoneAccountExporter(account, instance.collectErrors, instance.zoneChanges, instance.monitorStatusChanges)
+		if ok {
+			newAccount.snapshot = oldAccount.currentSnapshot()
+			newAccount.zoneChangeTracker = oldAccount.zoneChangeTracker
+			newAccount.monitorStatusTracker = oldAccount.monitorStatusTracker
+		}
+		newAccounts = append(newAccounts, newAccount)
+	}
+	instance.accounts = newAccounts
+	instance.accountsLock.Unlock()
+
+	for _, oldAccount := range oldAccounts {
+		oldAccount.close(!namesOfNewAccounts[oldAccount.name])
+	}
+
+	if instance.trigger != nil {
+		select {
+		case instance.trigger <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (instance *NsoneExporter) currentAccounts() []*nsoneAccountExporter {
+	instance.accountsLock.RLock()
+	defer instance.accountsLock.RUnlock()
+	return instance.accounts
+}
 
-	up     prometheus.Gauge
-	points map[string]*prometheus.GaugeVec
+// allPoints contains every point that could be exported by any settings.
+var allPoints = newAllPoints()
+
+// newAllPoints creates every point that could be exported by any settings. The drift points
+// are appended explicitly because they depend on the zone files and not on a matcher.
+func newAllPoints() map[string]*prometheus.GaugeVec {
+	result := newPointsFor(NsoneExportSettings{
+		UsageByHourFilter:    model.NewRegexpOrPanic(".*"),
+		UsageByDayFilter:     model.NewRegexpOrPanic(".*"),
+		UsageByMonthFilter:   model.NewRegexpOrPanic(".*"),
+		UsageGraph:           model.UG_LAST_BUCKET,
+		UsageOfAccount:       true,
+		UsageOfZonesFilter:   model.NewRegexpOrPanic(".*"),
+		UsageOfRecordsFilter: model.NewRegexpOrPanic(".*"),
+		QpsOfAccount:         true,
+		QpsOfZonesFilter:     model.NewRegexpOrPanic(".*"),
+		QpsOfRecordsFilter:   model.NewRegexpOrPanic(".*"),
+
+		InventoryOfZonesFilter:   model.NewRegexpOrPanic(".*"),
+		InventoryOfRecordsFilter: model.NewRegexpOrPanic(".*"),
+		InventoryOfAnswersFilter: model.NewRegexpOrPanic(".*"),
+
+		PulsarFilter:   model.NewRegexpOrPanic(".*"),
+		MonitorsFilter: model.NewRegexpOrPanic(".*"),
+		MonitorMetrics: true,
+
+		DataFeedsFilter: model.NewRegexpOrPanic(".*"),
+	})
+	appendDrift(&result)
+	return result
 }
 
-func NewNsoneExporter(accessToken string, timeout time.Duration, numberOfWorkers int, nsoneNumberOfConcurrentConnections int, settings NsoneExportSettings) *NsoneExporter {
+func newPointsFor(settings NsoneExportSettings) map[string]*prometheus.GaugeVec {
 	points := map[string]*prometheus.GaugeVec{}
+	appendGaugeWith(&points, "unknown_record_types", "Number of records with types that are not known by this exporter.", "type")
 	if settings.QpsOfAccount {
 		appendGauge(&points, "qps_account", "Queries per second of whole account.")
 	}
@@ -56,155 +224,296 @@ func NewNsoneExporter(accessToken string, timeout time.Duration, numberOfWorkers
 	if settings.UsageOfRecordsFilter.HasValue() {
 		appendUsages(&points, "usage_records", "Export usages of all records ", settings)
 	}
-
-	return &NsoneExporter{
-		settings:   settings,
-		client:     model.NewClient(accessToken, timeout, nsoneNumberOfConcurrentConnections),
-		workerPool: utils.NewWorkerPool(numberOfWorkers, numberOfWorkers),
-		up: prometheus.NewGauge(prometheus.GaugeOpts{
-			Namespace: namespace,
-			Name:      "up",
-			Help:      "Was the NSONE instance query successful?",
-		}),
-		points: points,
+	if settings.InventoryOfZonesFilter.HasValue() {
+		appendZoneInventory(&points)
+	}
+	if settings.InventoryOfRecordsFilter.HasValue() {
+		appendRecordInventory(&points)
+	}
+	if settings.InventoryOfAnswersFilter.HasValue() {
+		appendAnswerInventory(&points)
+	}
+	if len(settings.DriftZoneFiles) > 0 {
+		appendDrift(&points)
+	}
+	if settings.PulsarFilter.HasValue() {
+		appendPulsar(&points)
+	}
+	if settings.MonitorsFilter.HasValue() {
+		appendMonitoring(&points)
+		if settings.MonitorMetrics {
+			appendMonitoringMetrics(&points)
+		}
+	}
+	if settings.DataFeedsFilter.HasValue() {
+		appendDataFeeds(&points)
 	}
+	return points
 }
 
 func appendUsages(to *map[string]*prometheus.GaugeVec, namePrefix string, helpPrefix string, settings NsoneExportSettings) {
 	if settings.UsageByHourFilter.HasValue() {
-		appendGauge(to, namePrefix+"_hourly", helpPrefix+"by hour.")
+		appendUsage(to, namePrefix+"_hourly", helpPrefix+"by hour", settings)
 	}
 	if settings.UsageByDayFilter.HasValue() {
-		appendGauge(to, namePrefix+"_daily", helpPrefix+"by day.")
+		appendUsage(to, namePrefix+"_daily", helpPrefix+"by day", settings)
 	}
 	if settings.UsageByMonthFilter.HasValue() {
-		appendGauge(to, namePrefix+"_monthly", helpPrefix+"by month.")
+		appendUsage(to, namePrefix+"_monthly", helpPrefix+"by month", settings)
+	}
+}
+
+func appendUsage(to *map[string]*prometheus.GaugeVec, name string, help string, settings NsoneExportSettings) {
+	appendGauge(to, name, help+".")
+	if settings.UsageGraph.IsEnabled() {
+		appendGauge(to, name+usageGraphSuffix, help+" of the last complete bucket of the usage graph.")
 	}
 }
 
 func appendGauge(to *map[string]*prometheus.GaugeVec, name string, help string) {
-	labels := []string{}
+	labels := []string{
+		"account",
+	}
 	if strings.HasSuffix(name, "_zones") || strings.Contains(name, "_zones_") {
 		labels = []string{
+			"account",
 			"zone",
 		}
 	}
 	if strings.HasSuffix(name, "_records") || strings.Contains(name, "_records_") {
 		labels = []string{
+			"account",
 			"zone",
 			"record",
 			"recordType",
 		}
 	}
+	appendGaugeWith(to, name, help, labels[1:]...)
+}
+
+// appendGaugeWith appends a gauge with the given labels in addition to the 'account' label.
+func appendGaugeWith(to *map[string]*prometheus.GaugeVec, name string, help string, labels ...string) {
 	(*to)[name] = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: namespace,
 		Name:      name,
 		Help:      help,
-	}, labels)
+	}, append([]string{"account"}, labels...))
 }
 
 // Describe describes all the metrics ever exported by the
-// exporter. It implements prometheus.Collector.
+// exporter (also after a Reload). It implements prometheus.Collector.
 func (instance *NsoneExporter) Describe(ch chan<- *prometheus.Desc) {
-	ch <- instance.up.Desc()
-	for _, gauge := range instance.points {
+	ch <- instance.up
+	ch <- instance.lastCollectionTimestamp
+	ch <- instance.collectorSuccess
+	instance.collectErrors.Describe(ch)
+	instance.zoneChanges.Describe(ch)
+	instance.monitorStatusChanges.Describe(ch)
+	ch <- instance.rateLimitRemaining
+	ch <- instance.throttledRequests
+	ch <- instance.rateLimitedRequests
+	ch <- instance.apiRequestsInFlight
+	apiRequests.Describe(ch)
+	apiRequestDuration.Describe(ch)
+	apiRetries.Describe(ch)
+	for _, gauge := range allPoints {
 		gauge.Describe(ch)
 	}
 
 }
 
-// Collect fetches the stats from configured nsone and
-// delivers them as Prometheus metrics. It implements prometheus.Collector.
+// Collect delivers the stats of the latest collection of every account as Prometheus
+// metrics. If no collection interval was started it fetches the stats from configured
+// nsone first. It implements prometheus.Collector.
 func (instance *NsoneExporter) Collect(ch chan<- prometheus.Metric) {
-	instance.collectionLock.Lock() // To protect metrics from concurrent collects.
-	defer instance.collectionLock.Unlock()
-
-	start := time.Now()
-	log.Info("Collecting...")
+	instance.collectWith(context.Background(), ch)
+}
 
-	for _, gauge := range instance.points {
-		gauge.Reset()
+// WithContext returns a prometheus.Collector that behaves like this exporter but cancels
+// the fetching of the stats from configured nsone if ctx is done. In this case everything
+// that was complete until then is delivered.
+func (instance *NsoneExporter) WithContext(ctx context.Context) prometheus.Collector {
+	return &nsoneContextCollector{
+		exporter: instance,
+		ctx:      ctx,
 	}
+}
 
-	zones, err := instance.client.GetZones(true)
-	if err == nil {
-		log.Infof("Found %d active zones.", len(*zones))
+type nsoneContextCollector struct {
+	exporter *NsoneExporter
+	ctx      context.Context
+}
 
-		futures := &utils.WorkerFutures{}
-		instance.exportUsageIfRequired(zones, futures)
-		instance.exportQpsIfRequired(zones, futures)
+func (instance *nsoneContextCollector) Describe(ch chan<- *prometheus.Desc) {
+	instance.exporter.Describe(ch)
+}
+
+func (instance *nsoneContextCollector) Collect(ch chan<- prometheus.Metric) {
+	instance.exporter.collectWith(instance.ctx, ch)
+}
 
-		log.Infof("%d tasks enqueued.", len(*futures))
+func (instance *NsoneExporter) collectWith(ctx context.Context, ch chan<- prometheus.Metric) {
+	if instance.collectionInterval <= 0 {
+		instance.collect(ctx)
+	}
+
+	instance.collectErrors.Collect(ch)
+	instance.zoneChanges.Collect(ch)
+	instance.monitorStatusChanges.Collect(ch)
+	apiRequests.Collect(ch)
+	apiRequestDuration.Collect(ch)
+	apiRetries.Collect(ch)
+	for _, account := range instance.currentAccounts() {
+		instance.collectSnapshotOf(account, ch)
+		instance.collectClientStatsOf(account, ch)
+	}
+}
 
-		err = futures.Wait()
+func (instance *NsoneExporter) collectClientStatsOf(account *nsoneAccountExporter, ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(instance.apiRequestsInFlight, prometheus.GaugeValue, float64(account.client.NumberOfActiveConnections()), account.name)
+	stats := account.client.RateLimiterStats()
+	if stats.Known {
+		ch <- prometheus.MustNewConstMetric(instance.rateLimitRemaining, prometheus.GaugeValue, stats.Remaining, account.name)
+	}
+	ch <- prometheus.MustNewConstMetric(instance.throttledRequests, prometheus.CounterValue, float64(stats.Throttled), account.name)
+	ch <- prometheus.MustNewConstMetric(instance.rateLimitedRequests, prometheus.CounterValue, float64(stats.RateLimited), account.name)
+}
 
+func (instance *NsoneExporter) collectSnapshotOf(account *nsoneAccountExporter, ch chan<- prometheus.Metric) {
+	snapshot := account.currentSnapshot()
+	if snapshot == nil {
+		ch <- prometheus.MustNewConstMetric(instance.up, prometheus.GaugeValue, 0, account.name)
+		return
+	}
+	for _, point := range snapshot.points {
+		collectPointWithTimestamps(point, snapshot.timestamps, ch)
 	}
-	if err == nil {
-		for _, point := range instance.points {
-			point.Collect(ch)
+	for collector, success := range snapshot.successes {
+		value := 0.0
+		if success {
+			value = 1
 		}
+		ch <- prometheus.MustNewConstMetric(instance.collectorSuccess, prometheus.GaugeValue, value, account.name, collector)
 	}
+	ch <- prometheus.MustNewConstMetric(instance.up, prometheus.GaugeValue, snapshot.up, account.name)
+	ch <- prometheus.MustNewConstMetric(instance.lastCollectionTimestamp, prometheus.GaugeValue, float64(snapshot.timestamp.UnixNano())/1e9, account.name)
+}
 
-	duration := time.Now().Sub(start)
-	if err != nil {
-		log.Errorf("Collecting... FAILED! (duration: %v) Got: %v", duration, err)
-		instance.up.Set(0)
-	} else {
-		log.Infof("Collecting... DONE! (duration: %v)", duration)
-		instance.up.Set(1)
+// collectPointWithTimestamps collects the given point into ch. Every metric that has an
+// entry in timestamps is delivered with this explicit timestamp.
+func collectPointWithTimestamps(point *prometheus.GaugeVec, timestamps map[prometheus.Metric]time.Time, ch chan<- prometheus.Metric) {
+	if len(timestamps) == 0 {
+		point.Collect(ch)
+		return
+	}
+	metrics := make(chan prometheus.Metric)
+	go func() {
+		point.Collect(metrics)
+		close(metrics)
+	}()
+	for metric := range metrics {
+		if timestamp, ok := timestamps[metric]; ok {
+			metric = prometheus.NewMetricWithTimestamp(timestamp, metric)
+		}
+		ch <- metric
 	}
-	instance.up.Collect(ch)
 }
 
-func (instance *NsoneExporter) exportUsageIfRequired(zones *model.Zones, registerAt *utils.WorkerFutures) {
-	instance.exportAccountUsageIfRequired(registerAt)
-	instance.exportZoneUsagesIfRequired(registerAt)
-	instance.exportRecordUsagesIfRequired(zones, registerAt)
+// StartCollectingEvery starts a background collection every given interval. From now on
+// Collect will only deliver the stats of the latest background collection.
+func (instance *NsoneExporter) StartCollectingEvery(interval time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	instance.collectionInterval = interval
+	instance.stop = cancel
+	instance.trigger = make(chan struct{}, 1)
+	go instance.collectEvery(ctx, interval, instance.trigger)
 }
 
-func (instance *NsoneExporter) exportAccountUsageIfRequired(registerAt *utils.WorkerFutures) {
+// Stop stops a background collection started with StartCollectingEvery. A currently
+// running collection is cancelled.
+func (instance *NsoneExporter) Stop() {
+	if instance.stop != nil {
+		instance.stop()
+		instance.stop = nil
+	}
+}
+
+func (instance *NsoneExporter) collectEvery(ctx context.Context, interval time.Duration, trigger <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		instance.collect(ctx)
+		select {
+		case <-ticker.C:
+		case <-trigger:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// collect fetches the stats of all accounts in parallel.
+func (instance *NsoneExporter) collect(ctx context.Context) {
+	wg := sync.WaitGroup{}
+	for _, account := range instance.currentAccounts() {
+		wg.Add(1)
+		go func(account *nsoneAccountExporter) {
+			defer wg.Done()
+			account.collect(ctx)
+		}(account)
+	}
+	wg.Wait()
+}
+
+func (instance *nsoneAccountExporter) exportUsageIfRequired(ctx context.Context, zones *model.Zones, target *nsoneCollection) {
+	instance.exportAccountUsageIfRequired(ctx, target)
+	instance.exportZoneUsagesIfRequired(ctx, target)
+	instance.exportRecordUsagesIfRequired(ctx, zones, target)
+}
+
+func (instance *nsoneAccountExporter) exportAccountUsageIfRequired(ctx context.Context, target *nsoneCollection) {
 	if instance.settings.UsageOfAccount {
 		if instance.settings.UsageByHourFilter.MatchString("account") {
-			registerAt.Submit(instance.workerPool, func() error {
-				usage, err := instance.client.GetAccountUsage(model.P_HOURLY)
+			target.Submit(ctx, instance.workerPool, "usage_account", "/stats/usage", "", func() error {
+				usage, err := instance.client.GetAccountUsage(ctx, model.P_HOURLY)
 				if err != nil {
 					return err
 				}
-				return instance.setPoint("usage_account_hourly", usage.Queries, "", "", model.RT_NONE)
+				return target.setUsagePoint("usage_account_hourly", usage, "", "", model.RT_NONE)
 			})
 		}
 		if instance.settings.UsageByDayFilter.MatchString("account") {
-			registerAt.Submit(instance.workerPool, func() error {
-				usage, err := instance.client.GetAccountUsage(model.P_DAILY)
+			target.Submit(ctx, instance.workerPool, "usage_account", "/stats/usage", "", func() error {
+				usage, err := instance.client.GetAccountUsage(ctx, model.P_DAILY)
 				if err != nil {
 					return err
 				}
-				return instance.setPoint("usage_account_daily", usage.Queries, "", "", model.RT_NONE)
+				return target.setUsagePoint("usage_account_daily", usage, "", "", model.RT_NONE)
 			})
 		}
 		if instance.settings.UsageByMonthFilter.MatchString("account") {
-			registerAt.Submit(instance.workerPool, func() error {
-				usage, err := instance.client.GetAccountUsage(model.P_MONTHLY)
+			target.Submit(ctx, instance.workerPool, "usage_account", "/stats/usage", "", func() error {
+				usage, err := instance.client.GetAccountUsage(ctx, model.P_MONTHLY)
 				if err != nil {
 					return err
 				}
-				return instance.setPoint("usage_account_monthly", usage.Queries, "", "", model.RT_NONE)
+				return target.setUsagePoint("usage_account_monthly", usage, "", "", model.RT_NONE)
 			})
 		}
 	}
 }
 
-func (instance *NsoneExporter) exportZoneUsagesIfRequired(registerAt *utils.WorkerFutures) {
+func (instance *nsoneAccountExporter) exportZoneUsagesIfRequired(ctx context.Context, target *nsoneCollection) {
 	if instance.settings.UsageOfZonesFilter.HasValue() {
 		if instance.settings.UsageByHourFilter.HasValue() {
-			registerAt.Submit(instance.workerPool, func() error {
-				usages, err := instance.client.GetZonesUsage(model.P_HOURLY)
+			target.Submit(ctx, instance.workerPool, "usage_zones", "/stats/usage", "", func() error {
+				usages, err := instance.client.GetZonesUsage(ctx, model.P_HOURLY)
 				if err != nil {
 					return err
 				}
 				for _, usage := range *usages {
 					if instance.settings.UsageByHourFilter.MatchString(usage.Zone) && instance.settings.UsageOfZonesFilter.MatchString(usage.Zone) {
-						err = instance.setPoint("usage_zones_hourly", usage.Queries, usage.Zone, "", model.RT_NONE)
+						err = target.setUsagePoint("usage_zones_hourly", usage, usage.Zone, "", model.RT_NONE)
 						if err != nil {
 							return err
 						}
@@ -214,14 +523,14 @@ func (instance *NsoneExporter) exportZoneUsagesIfRequired(registerAt *utils.Work
 			})
 		}
 		if instance.settings.UsageByDayFilter.HasValue() {
-			registerAt.Submit(instance.workerPool, func() error {
-				usages, err := instance.client.GetZonesUsage(model.P_DAILY)
+			target.Submit(ctx, instance.workerPool, "usage_zones", "/stats/usage", "", func() error {
+				usages, err := instance.client.GetZonesUsage(ctx, model.P_DAILY)
 				if err != nil {
 					return err
 				}
 				for _, usage := range *usages {
-					if instance.settings.UsageByHourFilter.MatchString(usage.Zone) && instance.settings.UsageOfZonesFilter.MatchString(usage.Zone) {
-						err = instance.setPoint("usage_zones_daily", usage.Queries, usage.Zone, "", model.RT_NONE)
+					if instance.settings.UsageByDayFilter.MatchString(usage.Zone) && instance.settings.UsageOfZonesFilter.MatchString(usage.Zone) {
+						err = target.setUsagePoint("usage_zones_daily", usage, usage.Zone, "", model.RT_NONE)
 						if err != nil {
 							return err
 						}
@@ -231,14 +540,14 @@ func (instance *NsoneExporter) exportZoneUsagesIfRequired(registerAt *utils.Work
 			})
 		}
 		if instance.settings.UsageByMonthFilter.HasValue() {
-			registerAt.Submit(instance.workerPool, func() error {
-				usages, err := instance.client.GetZonesUsage(model.P_MONTHLY)
+			target.Submit(ctx, instance.workerPool, "usage_zones", "/stats/usage", "", func() error {
+				usages, err := instance.client.GetZonesUsage(ctx, model.P_MONTHLY)
 				if err != nil {
 					return err
 				}
 				for _, usage := range *usages {
-					if instance.settings.UsageByHourFilter.MatchString(usage.Zone) && instance.settings.UsageOfZonesFilter.MatchString(usage.Zone) {
-						err = instance.setPoint("usage_zones_monthly", usage.Queries, usage.Zone, "", model.RT_NONE)
+					if instance.settings.UsageByMonthFilter.MatchString(usage.Zone) && instance.settings.UsageOfZonesFilter.MatchString(usage.Zone) {
+						err = target.setUsagePoint("usage_zones_monthly", usage, usage.Zone, "", model.RT_NONE)
 						if err != nil {
 							return err
 						}
@@ -250,27 +559,27 @@ func (instance *NsoneExporter) exportZoneUsagesIfRequired(registerAt *utils.Work
 	}
 }
 
-func (instance *NsoneExporter) exportRecordUsagesIfRequired(zones *model.Zones, registerAt *utils.WorkerFutures) {
+func (instance *nsoneAccountExporter) exportRecordUsagesIfRequired(ctx context.Context, zones *model.Zones, target *nsoneCollection) {
 	if instance.settings.UsageOfRecordsFilter.HasValue() {
 		for _, zone := range *zones {
 			if len(zone.Link) <= 0 && instance.settings.UsageOfRecordsFilter.MatchString(zone.Name) {
-				instance.exportRecordUsagesOfZoneIfRequired(zone, registerAt)
+				instance.exportRecordUsagesOfZoneIfRequired(ctx, zone, target)
 			}
 		}
 	}
 }
 
-func (instance *NsoneExporter) exportRecordUsagesOfZoneIfRequired(zone *model.Zone, registerAt *utils.WorkerFutures) {
+func (instance *nsoneAccountExporter) exportRecordUsagesOfZoneIfRequired(ctx context.Context, zone *model.Zone, target *nsoneCollection) {
 	if instance.settings.UsageByHourFilter.MatchString(zone.Name) {
-		registerAt.Submit(instance.workerPool, func() error {
-			usages, err := instance.client.GetRecordsUsage(zone.Name, model.P_HOURLY)
+		target.Submit(ctx, instance.workerPool, "usage_records", "/stats/usage/{zone}", zone.Name, func() error {
+			usages, err := instance.client.GetRecordsUsage(ctx, zone.Name, model.P_HOURLY)
 			if err != nil {
 				return err
 			}
 			for _, usage := range *usages {
 				fullRecord := usage.Type.String() + " " + usage.Domain
 				if instance.settings.UsageByHourFilter.MatchString(fullRecord) && instance.settings.UsageOfRecordsFilter.MatchString(fullRecord) {
-					err = instance.setPoint("usage_records_hourly", usage.Queries, usage.Zone, usage.Domain, usage.Type)
+					err = target.setUsagePoint("usage_records_hourly", usage, usage.Zone, usage.Domain, usage.Type)
 					if err != nil {
 						return err
 					}
@@ -280,15 +589,15 @@ func (instance *NsoneExporter) exportRecordUsagesOfZoneIfRequired(zone *model.Zo
 		})
 	}
 	if instance.settings.UsageByDayFilter.MatchString(zone.Name) {
-		registerAt.Submit(instance.workerPool, func() error {
-			usages, err := instance.client.GetRecordsUsage(zone.Name, model.P_DAILY)
+		target.Submit(ctx, instance.workerPool, "usage_records", "/stats/usage/{zone}", zone.Name, func() error {
+			usages, err := instance.client.GetRecordsUsage(ctx, zone.Name, model.P_DAILY)
 			if err != nil {
 				return err
 			}
 			for _, usage := range *usages {
 				fullRecord := usage.Type.String() + " " + usage.Domain
 				if instance.settings.UsageByDayFilter.MatchString(fullRecord) && instance.settings.UsageOfRecordsFilter.MatchString(fullRecord) {
-					err = instance.setPoint("usage_records_daily", usage.Queries, usage.Zone, usage.Domain, usage.Type)
+					err = target.setUsagePoint("usage_records_daily", usage, usage.Zone, usage.Domain, usage.Type)
 					if err != nil {
 						return err
 					}
@@ -298,15 +607,15 @@ func (instance *NsoneExporter) exportRecordUsagesOfZoneIfRequired(zone *model.Zo
 		})
 	}
 	if instance.settings.UsageByMonthFilter.MatchString(zone.Name) {
-		registerAt.Submit(instance.workerPool, func() error {
-			usages, err := instance.client.GetRecordsUsage(zone.Name, model.P_MONTHLY)
+		target.Submit(ctx, instance.workerPool, "usage_records", "/stats/usage/{zone}", zone.Name, func() error {
+			usages, err := instance.client.GetRecordsUsage(ctx, zone.Name, model.P_MONTHLY)
 			if err != nil {
 				return err
 			}
 			for _, usage := range *usages {
 				fullRecord := usage.Type.String() + " " + usage.Domain
 				if instance.settings.UsageByMonthFilter.MatchString(fullRecord) && instance.settings.UsageOfRecordsFilter.MatchString(fullRecord) {
-					err = instance.setPoint("usage_records_monthly", usage.Queries, usage.Zone, usage.Domain, usage.Type)
+					err = target.setUsagePoint("usage_records_monthly", usage, usage.Zone, usage.Domain, usage.Type)
 					if err != nil {
 						return err
 					}
@@ -317,88 +626,64 @@ func (instance *NsoneExporter) exportRecordUsagesOfZoneIfRequired(zone *model.Zo
 	}
 }
 
-func (instance *NsoneExporter) exportQpsIfRequired(zones *model.Zones, registerAt *utils.WorkerFutures) {
-	instance.exportAccountQpsIfRequired(registerAt)
-	instance.exportZonesQpsIfRequired(zones, registerAt)
-	instance.exportRecordsQpsIfRequired(zones, registerAt)
+func (instance *nsoneAccountExporter) exportQpsIfRequired(ctx context.Context, zones *model.Zones, target *nsoneCollection) {
+	instance.exportAccountQpsIfRequired(ctx, target)
+	instance.exportZonesQpsIfRequired(ctx, zones, target)
+	instance.exportRecordsQpsIfRequired(ctx, zones, target)
 }
 
-func (instance *NsoneExporter) exportAccountQpsIfRequired(registerAt *utils.WorkerFutures) {
+func (instance *nsoneAccountExporter) exportAccountQpsIfRequired(ctx context.Context, target *nsoneCollection) {
 	if instance.settings.QpsOfAccount {
-		registerAt.Submit(instance.workerPool, func() error {
-			qps, err := instance.client.GetAccountQps()
+		target.Submit(ctx, instance.workerPool, "qps_account", "/stats/qps", "", func() error {
+			qps, err := instance.client.GetAccountQps(ctx)
 			if err != nil {
 				return err
 			}
-			return instance.setPoint("qps_account", qps, "", "", model.RT_NONE)
+			return target.setPoint("qps_account", qps, "", "", model.RT_NONE)
 		})
 	}
 }
 
-func (instance *NsoneExporter) exportZonesQpsIfRequired(zones *model.Zones, registerAt *utils.WorkerFutures) {
+func (instance *nsoneAccountExporter) exportZonesQpsIfRequired(ctx context.Context, zones *model.Zones, target *nsoneCollection) {
 	if instance.settings.QpsOfZonesFilter.HasValue() {
 		for _, zone := range *zones {
-			instance.exportZoneQpsIfRequired(zone, registerAt)
+			instance.exportZoneQpsIfRequired(ctx, zone, target)
 		}
 	}
 }
 
-func (instance *NsoneExporter) exportZoneQpsIfRequired(zone *model.Zone, registerAt *utils.WorkerFutures) {
+func (instance *nsoneAccountExporter) exportZoneQpsIfRequired(ctx context.Context, zone *model.Zone, target *nsoneCollection) {
 	if len(zone.Link) <= 0 && instance.settings.QpsOfZonesFilter.MatchString(zone.Name) {
-		registerAt.Submit(instance.workerPool, func() error {
-			qps, err := instance.client.GetZoneQps(zone.Name)
+		target.Submit(ctx, instance.workerPool, "qps_zones", "/stats/qps/{zone}", zone.Name, func() error {
+			qps, err := instance.client.GetZoneQps(ctx, zone.Name)
 			if err != nil {
 				return err
 			}
-			return instance.setPoint("qps_zones", qps, zone.Name, "", model.RT_NONE)
+			return target.setPoint("qps_zones", qps, zone.Name, "", model.RT_NONE)
 		})
 	}
 }
 
-func (instance *NsoneExporter) exportRecordsQpsIfRequired(zones *model.Zones, registerAt *utils.WorkerFutures) {
+func (instance *nsoneAccountExporter) exportRecordsQpsIfRequired(ctx context.Context, zones *model.Zones, target *nsoneCollection) {
 	if instance.settings.QpsOfRecordsFilter.HasValue() {
 		for _, zone := range *zones {
 			if len(zone.Link) <= 0 && instance.settings.QpsOfRecordsFilter.MatchString(zone.Name) {
 				for _, record := range zone.Records {
-					instance.exportRecordQpsIfRequired(zone, record, registerAt)
+					instance.exportRecordQpsIfRequired(ctx, zone, record, target)
 				}
 			}
 		}
 	}
 }
 
-func (instance *NsoneExporter) exportRecordQpsIfRequired(zone *model.Zone, record *model.Record, registerAt *utils.WorkerFutures) {
+func (instance *nsoneAccountExporter) exportRecordQpsIfRequired(ctx context.Context, zone *model.Zone, record *model.Record, target *nsoneCollection) {
 	if len(record.Link) <= 0 && instance.settings.QpsOfRecordsFilter.MatchString(record.Type.String() + " " + record.Name) {
-		registerAt.Submit(instance.workerPool, func() error {
-			qps, err := instance.client.GetRecordQps(zone.Name, record.Name, record.Type)
+		target.Submit(ctx, instance.workerPool, "qps_records", "/stats/qps/{zone}/{record}/{type}", zone.Name, func() error {
+			qps, err := instance.client.GetRecordQps(ctx, zone.Name, record.Name, record.Type)
 			if err != nil {
 				return err
 			}
-			return instance.setPoint("qps_records", qps, zone.Name, record.Name, record.Type)
+			return target.setPoint("qps_records", qps, zone.Name, record.Name, record.Type)
 		})
 	}
 }
-
-func (instance *NsoneExporter) setPoint(name string, value float64, zone string, record string, recordType model.RecordType) error {
-	instance.pointsLock.Lock() // To protect metrics from concurrent sets on points.
-	defer instance.pointsLock.Unlock()
-	labels := prometheus.Labels{}
-	if strings.HasSuffix(name, "_zones") || strings.Contains(name, "_zones_") {
-		labels["zone"] = zone
-	}
-	if strings.HasSuffix(name, "_records") || strings.Contains(name, "_records_") {
-		labels["zone"] = zone
-		labels["record"] = record
-		labels["recordType"] = recordType.String()
-	}
-	gaugeVec := instance.points[name]
-	if gaugeVec == nil {
-		return fmt.Errorf("Try to set point with name %s but it was not crated before.", name)
-	}
-	gauge, err := gaugeVec.GetMetricWith(labels)
-	if err != nil {
-		return fmt.Errorf("Try to set point %s but got: %v", name, err)
-	}
-	gauge.Set(value)
-	return nil
-}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/echocat/nsone_exporter/model"
+	"github.com/echocat/nsone_exporter/nsonetest"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const testAccessToken = "test-token"
+
+// newTestServer creates a fake with the zones example.com (two records, usages and qps)
+// and other.org (without any records).
+func newTestServer() *nsonetest.Server {
+	server := nsonetest.NewServer(testAccessToken)
+	server.AddZone(&model.Zone{Name: "example.com", TTL: 3600, Serial: 5, Refresh: 1, Retry: 2, Expiry: 3, NxTTL: 4, Records: []*model.Record{
+		{Name: "www.example.com", Type: model.RT_A, ShortAnswers: []string{"1.2.3.4"}, TTL: 60},
+		{Name: "example.com", Type: model.RT_MX, ShortAnswers: []string{"10 mx.example.com"}, TTL: 60},
+	}})
+	server.AddZone(&model.Zone{Name: "other.org", TTL: 3600})
+	server.SetAccountUsage(model.P_MONTHLY, 42, [][]float64{{1500000000, 2}, {1500003600, 3}})
+	server.SetZoneUsage("example.com", model.P_MONTHLY, 40, nil)
+	server.SetAccountQps(9)
+	server.SetZoneQps("example.com", 8)
+	server.SetRecordQps("example.com", "www.example.com", model.RT_A, 7)
+	return server
+}
+
+// newTestSettings exports the monthly usages and the qps of everything. Everything else is off.
+func newTestSettings() NsoneExportSettings {
+	return NsoneExportSettings{
+		UsageByHourFilter:        model.NewRegexpOrPanic("off"),
+		UsageByDayFilter:         model.NewRegexpOrPanic("off"),
+		UsageByMonthFilter:       model.NewRegexpOrPanic(".*"),
+		UsageOfAccount:           true,
+		UsageOfZonesFilter:       model.NewRegexpOrPanic(".*"),
+		UsageOfRecordsFilter:     model.NewRegexpOrPanic(".*"),
+		QpsOfAccount:             true,
+		QpsOfZonesFilter:         model.NewRegexpOrPanic(".*"),
+		QpsOfRecordsFilter:       model.NewRegexpOrPanic(".*"),
+		InventoryOfZonesFilter:   model.NewRegexpOrPanic("off"),
+		InventoryOfRecordsFilter: model.NewRegexpOrPanic("off"),
+		InventoryOfAnswersFilter: model.NewRegexpOrPanic("off"),
+		PulsarFilter:             model.NewRegexpOrPanic("off"),
+		MonitorsFilter:           model.NewRegexpOrPanic("off"),
+		DataFeedsFilter:          model.NewRegexpOrPanic("off"),
+	}
+}
+
+func newTestAccount(t *testing.T, server *nsonetest.Server, name string, settings NsoneExportSettings) NsoneAccount {
+	client, err := server.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NsoneAccount{
+		Name:            name,
+		Client:          client,
+		NumberOfWorkers: 5,
+		Settings:        settings,
+	}
+}
+
+func newTestClient(t *testing.T, options model.ClientOptions) *model.Client {
+	client, err := model.NewClient(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// newObservedTestAccount creates an account like newTestAccount which records its requests
+// as api metrics.
+func newObservedTestAccount(t *testing.T, server *nsonetest.Server, name string, settings NsoneExportSettings) NsoneAccount {
+	options := server.ClientOptions()
+	options.Observer = newApiObserver(name)
+	result := newTestAccount(t, server, name, settings)
+	result.Client = newTestClient(t, options)
+	return result
+}
+
+// gather collects the given collector and returns the value of every sample by
+// 'name{label="value",...}' (labels in alphabetical order).
+func gather(t *testing.T, collector prometheus.Collector) map[string]float64 {
+	registry := prometheus.NewPedanticRegistry()
+	if err := registry.Register(collector); err != nil {
+		t.Fatal(err)
+	}
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := map[string]float64{}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			labels := []string{}
+			for _, label := range metric.GetLabel() {
+				labels = append(labels, fmt.Sprintf("%s=%q", label.GetName(), label.GetValue()))
+			}
+			sort.Strings(labels)
+			result[family.GetName()+"{"+strings.Join(labels, ",")+"}"] = valueOf(family.GetType(), metric)
+		}
+	}
+	return result
+}
+
+func valueOf(metricType dto.MetricType, metric *dto.Metric) float64 {
+	switch metricType {
+	case dto.MetricType_COUNTER:
+		return metric.GetCounter().GetValue()
+	case dto.MetricType_GAUGE:
+		return metric.GetGauge().GetValue()
+	case dto.MetricType_HISTOGRAM:
+		return float64(metric.GetHistogram().GetSampleCount())
+	case dto.MetricType_SUMMARY:
+		return float64(metric.GetSummary().GetSampleCount())
+	}
+	return metric.GetUntyped().GetValue()
+}
+
+// assertSamples fails if one of the expected samples is missing or has another value.
+func assertSamples(t *testing.T, actual map[string]float64, expected map[string]float64) {
+	t.Helper()
+	for sample, value := range expected {
+		if actualValue, ok := actual[sample]; !ok {
+			t.Errorf("Expected sample %s but there is none.", sample)
+		} else if actualValue != value {
+			t.Errorf("Expected sample %s to be %v but it is %v.", sample, value, actualValue)
+		}
+	}
+}
+
+// refuteSamples fails if there is any sample that starts with one of the given prefixes.
+func refuteSamples(t *testing.T, actual map[string]float64, prefixes ...string) {
+	t.Helper()
+	for sample := range actual {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(sample, prefix) {
+				t.Errorf("Expected no sample %s but there is one.", sample)
+			}
+		}
+	}
+}
+
+func TestCollectExportsUsageAndQpsOfAccount(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	exporter := NewNsoneExporter([]NsoneAccount{newTestAccount(t, server, "a", newTestSettings())})
+
+	samples := gather(t, exporter)
+
+	assertSamples(t, samples, map[string]float64{
+		`nsone_up{account="a"}`:                                                                     1,
+		`nsone_collector_success{account="a",collector="zones"}`:                                    1,
+		`nsone_usage_account_monthly{account="a"}`:                                                  42,
+		`nsone_usage_zones_monthly{account="a",zone="example.com"}`:                                 40,
+		`nsone_qps_account{account="a"}`:                                                            9,
+		`nsone_qps_zones{account="a",zone="example.com"}`:                                           8,
+		`nsone_qps_records{account="a",record="www.example.com",recordType="A",zone="example.com"}`: 7,
+		`nsone_qps_records{account="a",record="example.com",recordType="MX",zone="example.com"}`:    0,
+	})
+	refuteSamples(t, samples, "nsone_usage_account_hourly", "nsone_zone_info")
+}
+
+func TestCollectFailsIfZonesCouldNotBeRetrieved(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	server.Inject("/zones", nsonetest.Fault{StatusCode: 500})
+	exporter := NewNsoneExporter([]NsoneAccount{newTestAccount(t, server, "a", newTestSettings())})
+
+	samples := gather(t, exporter)
+
+	assertSamples(t, samples, map[string]float64{
+		`nsone_up{account="a"}`: 0,
+		`nsone_collect_errors_total{account="a",endpoint="/zones",zone=""}`: 1,
+	})
+	refuteSamples(t, samples, "nsone_qps_", "nsone_usage_")
+}
+
+func TestCollectExportsEverythingElseIfSomeTasksFailed(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	server.NotFound("/stats/qps/example.com")
+	exporter := NewNsoneExporter([]NsoneAccount{newTestAccount(t, server, "a", newTestSettings())})
+
+	samples := gather(t, exporter)
+
+	assertSamples(t, samples, map[string]float64{
+		`nsone_up{account="a"}`: 1,
+		`nsone_collector_success{account="a",collector="qps_zones"}`:                                0,
+		`nsone_collector_success{account="a",collector="qps_account"}`:                              1,
+		`nsone_collect_errors_total{account="a",endpoint="/stats/qps/{zone}",zone="example.com"}`:   1,
+		`nsone_qps_account{account="a"}`:                                                            9,
+		`nsone_qps_zones{account="a",zone="other.org"}`:                                             0,
+		`nsone_qps_records{account="a",record="www.example.com",recordType="A",zone="example.com"}`: 7,
+	})
+	refuteSamples(t, samples, `nsone_qps_zones{account="a",zone="example.com"}`)
+}
+
+func TestCollectDeliversSnapshotOfBackgroundCollection(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	exporter := NewNsoneExporter([]NsoneAccount{newTestAccount(t, server, "a", newTestSettings())})
+	exporter.StartCollectingEvery(time.Hour)
+	defer exporter.Stop()
+	deadline := time.Now().Add(5 * time.Second)
+	for exporter.currentAccounts()[0].currentSnapshot() == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Background collection did not complete in time.")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	requests := server.Requests("/zones")
+
+	gather(t, exporter)
+	samples := gather(t, exporter)
+
+	assertSamples(t, samples, map[string]float64{
+		`nsone_up{account="a"}`:          1,
+		`nsone_qps_account{account="a"}`: 9,
+	})
+	if timestamp := samples[`nsone_last_collection_timestamp_seconds{account="a"}`]; timestamp <= 0 {
+		t.Errorf("Expected timestamp of the last collection but got: %v", timestamp)
+	}
+	if actual := server.Requests("/zones"); actual != requests {
+		t.Errorf("Expected no collection while gathering but /zones was requested %d times instead of %d.", actual, requests)
+	}
+}
+
+func TestCollectPacesRequestsByRateLimitOfApi(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	server.SetRateLimit(5, time.Second)
+	exporter := NewNsoneExporter([]NsoneAccount{newTestAccount(t, server, "a", newTestSettings())})
+
+	samples := gather(t, exporter)
+
+	assertSamples(t, samples, map[string]float64{
+		`nsone_up{account="a"}`:                              1,
+		`nsone_qps_account{account="a"}`:                     9,
+		`nsone_api_rate_limited_requests_total{account="a"}`: 0,
+	})
+	if throttled := samples[`nsone_api_throttled_requests_total{account="a"}`]; throttled <= 0 {
+		t.Errorf("Expected throttled requests but got: %v", throttled)
+	}
+	if remaining, ok := samples[`nsone_api_ratelimit_remaining{account="a"}`]; !ok || remaining < 0 || remaining > 5 {
+		t.Errorf("Expected remaining requests between 0 and 5 but got: %v", remaining)
+	}
+	refuteSamples(t, samples, "nsone_collect_errors_total")
+}
+
+// waitUntilClosed waits until the given account was closed by its close goroutine.
+func waitUntilClosed(t *testing.T, account *nsoneAccountExporter) {
+	t.Helper()
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		account.collecting <- struct{}{}
+		closed := account.closed
+		<-account.collecting
+		if closed {
+			return
+		}
+	}
+	t.Fatalf("Expected account %s to be closed.", account.name)
+}
+
+func TestReloadKeepsUnchangedAccountsAndReplacesChangedOnes(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	exporter := NewNsoneExporter([]NsoneAccount{
+		newTestAccount(t, server, "unchanged", newTestSettings()),
+		newTestAccount(t, server, "changed", newTestSettings()),
+	})
+	gather(t, exporter)
+	unchanged, changed := exporter.currentAccounts()[0], exporter.currentAccounts()[1]
+
+	changedSettings := newTestSettings()
+	changedSettings.QpsOfRecordsFilter = model.NewRegexpOrPanic("off")
+	exporter.Reload([]NsoneAccount{
+		newTestAccount(t, server, "unchanged", newTestSettings()),
+		newTestAccount(t, server, "changed", changedSettings),
+	})
+
+	accounts := exporter.currentAccounts()
+	if len(accounts) != 2 || accounts[0] != unchanged {
+		t.Errorf("Expected the unchanged account to be kept.")
+	}
+	if accounts[1] == changed || accounts[1].settings.QpsOfRecordsFilter.HasValue() {
+		t.Errorf("Expected the changed account to be replaced.")
+	}
+	if accounts[1].currentSnapshot() != changed.currentSnapshot() {
+		t.Errorf("Expected the snapshot of the changed account to be kept until its next collection.")
+	}
+	waitUntilClosed(t, changed)
+	unchanged.collecting <- struct{}{}
+	closed := unchanged.closed
+	<-unchanged.collecting
+	if closed {
+		t.Errorf("Expected the unchanged account not to be closed.")
+	}
+
+	samples := gather(t, exporter)
+
+	assertSamples(t, samples, map[string]float64{
+		`nsone_up{account="unchanged"}`: 1,
+		`nsone_up{account="changed"}`:   1,
+		`nsone_qps_records{account="unchanged",record="www.example.com",recordType="A",zone="example.com"}`: 7,
+	})
+	refuteSamples(t, samples, `nsone_qps_records{account="changed"`)
+}
+
+func TestReloadDeletesApiMetricsOfRemovedAccounts(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	exporter := NewNsoneExporter([]NsoneAccount{
+		newObservedTestAccount(t, server, "reload-kept", newTestSettings()),
+		newObservedTestAccount(t, server, "reload-removed", newTestSettings()),
+	})
+	gather(t, exporter)
+	removed := exporter.currentAccounts()[1]
+	requests, durations := gather(t, apiRequests), gather(t, apiRequestDuration)
+	kept := `{account="reload-kept",code="200",endpoint="/zones"}`
+	if requests[`nsone_api_requests_total{account="reload-removed",code="200",endpoint="/zones"}`] <= 0 {
+		t.Fatalf("Expected requests of the account that will be removed.")
+	}
+
+	exporter.Reload([]NsoneAccount{
+		newObservedTestAccount(t, server, "reload-kept", newTestSettings()),
+	})
+	waitUntilClosed(t, removed)
+
+	assertSamples(t, gather(t, apiRequests), map[string]float64{
+		"nsone_api_requests_total" + kept: requests["nsone_api_requests_total"+kept],
+	})
+	assertSamples(t, gather(t, apiRequestDuration), map[string]float64{
+		`nsone_api_request_duration_seconds{account="reload-kept",endpoint="/zones"}`: durations[`nsone_api_request_duration_seconds{account="reload-kept",endpoint="/zones"}`],
+	})
+	refuteSamples(t, gather(t, apiRequests), `nsone_api_requests_total{account="reload-removed"`)
+	refuteSamples(t, gather(t, apiRequestDuration), `nsone_api_request_duration_seconds{account="reload-removed"`)
+}
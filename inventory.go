@@ -0,0 +1,175 @@
+package main
+
+import (
+	"github.com/echocat/nsone_exporter/model"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"strings"
+)
+
+func appendZoneInventory(to *map[string]*prometheus.GaugeVec) {
+	appendGaugeWith(to, "zone_info", "Information about zones. Always 1.", "zone", "primary", "pool", "link")
+	appendGaugeWith(to, "zone_serial", "Serial of the SOA record of zones.", "zone")
+	appendGaugeWith(to, "zone_ttl_seconds", "TTL of the SOA record of zones.", "zone")
+	appendGaugeWith(to, "zone_refresh_seconds", "Refresh of the SOA record of zones.", "zone")
+	appendGaugeWith(to, "zone_retry_seconds", "Retry of the SOA record of zones.", "zone")
+	appendGaugeWith(to, "zone_expiry_seconds", "Expiry of the SOA record of zones.", "zone")
+	appendGaugeWith(to, "zone_nx_ttl_seconds", "TTL of negative responses of zones.", "zone")
+	appendGaugeWith(to, "zone_records", "Number of records of zones by type.", "zone", "type")
+	appendGaugeWith(to, "zone_last_change_timestamp_seconds", "Unix timestamp of the last detected change of the serial of zones.", "zone")
+	appendGaugeWith(to, "zone_secondary_last_xfr_timestamp_seconds", "Unix timestamp of the last transfer of secondary zones.", "zone")
+	appendGaugeWith(to, "zone_secondary_xfr_status", "Status of the last transfer of secondary zones. Always 1.", "zone", "status", "masters")
+	appendGaugeWith(to, "zone_secondary_error", "Did the last transfer of secondary zones fail?", "zone")
+	appendGaugeWith(to, "zone_secondary_expired", "Are secondary zones expired?", "zone")
+	appendGaugeWith(to, "zone_primary_secondaries", "Number of configured secondaries of zones NSONE is the primary of.", "zone")
+}
+
+func appendRecordInventory(to *map[string]*prometheus.GaugeVec) {
+	appendGaugeWith(to, "record_info", "Information about records. Always 1.", "zone", "record", "recordType", "link")
+	appendGaugeWith(to, "record_ttl_seconds", "TTL of records.", "zone", "record", "recordType")
+	appendGaugeWith(to, "record_tier", "Tier of records.", "zone", "record", "recordType")
+	appendGaugeWith(to, "record_answers", "Number of answers of records.", "zone", "record", "recordType")
+}
+
+// exportZoneInventoryIfRequired exports the details of the given (already expanded) zones
+// without any further request against nsone.
+func (instance *nsoneAccountExporter) exportZoneInventoryIfRequired(zones *model.Zones, target *nsoneCollection) {
+	if !instance.settings.InventoryOfZonesFilter.HasValue() {
+		return
+	}
+	target.expect("zone_inventory")
+	for _, zone := range *zones {
+		if instance.settings.InventoryOfZonesFilter.MatchString(zone.Name) {
+			if err := exportZoneInventoryOf(zone, target); err != nil {
+				target.failed("zone_inventory", "/zones/{zone}", zone.Name, err)
+			}
+		}
+	}
+}
+
+func exportZoneInventoryOf(zone *model.Zone, target *nsoneCollection) error {
+	zoneLabels := prometheus.Labels{"zone": zone.Name}
+	values := map[string]float64{
+		"zone_serial":          float64(zone.Serial),
+		"zone_ttl_seconds":     float64(zone.TTL),
+		"zone_refresh_seconds": float64(zone.Refresh),
+		"zone_retry_seconds":   float64(zone.Retry),
+		"zone_expiry_seconds":  float64(zone.Expiry),
+		"zone_nx_ttl_seconds":  float64(zone.NxTTL),
+	}
+	for name, value := range values {
+		if err := target.setPointWith(name, value, zoneLabels); err != nil {
+			return err
+		}
+	}
+	err := target.setPointWith("zone_info", 1, prometheus.Labels{
+		"zone":    zone.Name,
+		"primary": zone.PrimaryMaster,
+		"pool":    zone.Pool,
+		"link":    zone.Link,
+	})
+	if err != nil {
+		return err
+	}
+	if err := exportZoneTransferOf(zone, target); err != nil {
+		return err
+	}
+	numberOfRecords := map[model.RecordType]int{}
+	for _, record := range zone.Records {
+		numberOfRecords[record.Type]++
+	}
+	for recordType, count := range numberOfRecords {
+		err := target.setPointWith("zone_records", float64(count), prometheus.Labels{
+			"zone": zone.Name,
+			"type": recordType.String(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportZoneTransferOf exports the state of the transfers of the given zone if NSONE is a
+// secondary or the primary of it.
+func exportZoneTransferOf(zone *model.Zone, target *nsoneCollection) error {
+	zoneLabels := prometheus.Labels{"zone": zone.Name}
+	if primary := zone.Primary; primary != nil && primary.Enabled {
+		if err := target.setPointWith("zone_primary_secondaries", float64(len(primary.Secondaries)), zoneLabels); err != nil {
+			return err
+		}
+	}
+	secondary := zone.Secondary
+	if secondary == nil || !secondary.Enabled {
+		return nil
+	}
+	if secondary.LastXfr > 0 {
+		if err := target.setPointWith("zone_secondary_last_xfr_timestamp_seconds", float64(secondary.LastXfr), zoneLabels); err != nil {
+			return err
+		}
+	}
+	err := target.setPointWith("zone_secondary_xfr_status", 1, prometheus.Labels{
+		"zone":    zone.Name,
+		"status":  secondary.Status,
+		"masters": strings.Join(secondary.Masters(), ","),
+	})
+	if err != nil {
+		return err
+	}
+	failed := 0.0
+	if len(secondary.Error) > 0 {
+		failed = 1
+		log.Warnf("Last transfer of secondary zone %s of account %s failed: %s", zone.Name, target.account, secondary.Error)
+	}
+	if err := target.setPointWith("zone_secondary_error", failed, zoneLabels); err != nil {
+		return err
+	}
+	expired := 0.0
+	if secondary.Expired {
+		expired = 1
+	}
+	return target.setPointWith("zone_secondary_expired", expired, zoneLabels)
+}
+
+// exportRecordInventoryIfRequired exports the details of the records of the given (already
+// expanded) zones without any further request against nsone.
+func (instance *nsoneAccountExporter) exportRecordInventoryIfRequired(zones *model.Zones, target *nsoneCollection) {
+	if !instance.settings.InventoryOfRecordsFilter.HasValue() {
+		return
+	}
+	target.expect("record_inventory")
+	for _, zone := range *zones {
+		for _, record := range zone.Records {
+			fullRecord := record.Type.String() + " " + record.Name
+			if instance.settings.InventoryOfRecordsFilter.MatchString(fullRecord) {
+				if err := exportRecordInventoryOf(zone, record, target); err != nil {
+					target.failed("record_inventory", "/zones/{zone}", zone.Name, err)
+				}
+			}
+		}
+	}
+}
+
+func exportRecordInventoryOf(zone *model.Zone, record *model.Record, target *nsoneCollection) error {
+	recordLabels := prometheus.Labels{
+		"zone":       zone.Name,
+		"record":     record.Name,
+		"recordType": record.Type.String(),
+	}
+	values := map[string]float64{
+		"record_ttl_seconds": float64(record.TTL),
+		"record_tier":        float64(record.Tier),
+		"record_answers":     float64(len(record.ShortAnswers)),
+	}
+	for name, value := range values {
+		if err := target.setPointWith(name, value, recordLabels); err != nil {
+			return err
+		}
+	}
+	return target.setPointWith("record_info", 1, prometheus.Labels{
+		"zone":       zone.Name,
+		"record":     record.Name,
+		"recordType": record.Type.String(),
+		"link":       record.Link,
+	})
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/echocat/nsone_exporter/model"
+)
+
+func TestCollectExportsInventoryOfMatchingZones(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	server.AddZone(&model.Zone{Name: "linked.com", Link: "example.com", PrimaryMaster: "dns1.p01.nsone.net", Pool: "p01", TTL: 3600})
+	settings := newTestSettings()
+	settings.InventoryOfZonesFilter = model.NewRegexpOrPanic(`\.com$`)
+	exporter := NewNsoneExporter([]NsoneAccount{newTestAccount(t, server, "a", settings)})
+
+	samples := gather(t, exporter)
+
+	assertSamples(t, samples, map[string]float64{
+		`nsone_collector_success{account="a",collector="zone_inventory"}`:                                           1,
+		`nsone_zone_info{account="a",link="",pool="",primary="",zone="example.com"}`:                                1,
+		`nsone_zone_info{account="a",link="example.com",pool="p01",primary="dns1.p01.nsone.net",zone="linked.com"}`: 1,
+		`nsone_zone_serial{account="a",zone="example.com"}`:                                                         5,
+		`nsone_zone_ttl_seconds{account="a",zone="example.com"}`:                                                    3600,
+		`nsone_zone_refresh_seconds{account="a",zone="example.com"}`:                                                1,
+		`nsone_zone_retry_seconds{account="a",zone="example.com"}`:                                                  2,
+		`nsone_zone_expiry_seconds{account="a",zone="example.com"}`:                                                 3,
+		`nsone_zone_nx_ttl_seconds{account="a",zone="example.com"}`:                                                 4,
+		`nsone_zone_records{account="a",type="A",zone="example.com"}`:                                               1,
+		`nsone_zone_records{account="a",type="MX",zone="example.com"}`:                                              1,
+	})
+	refuteSamples(t, samples,
+		`nsone_zone_info{account="a",link="",pool="",primary="",zone="other.org"}`,
+		`nsone_zone_serial{account="a",zone="other.org"}`,
+		`nsone_zone_records{account="a",type="A",zone="linked.com"}`,
+	)
+}
+
+func TestZoneInventoryIsDisabledByDefault(t *testing.T) {
+	if settingsFromFlags().InventoryOfZonesFilter.HasValue() {
+		t.Errorf("Expected the inventory of zones to be disabled without -export.inventory-of-zones-filter.")
+	}
+}
+
+func TestCollectExportsInventoryOfMatchingRecords(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	server.AddZone(&model.Zone{Name: "other.net", TTL: 3600, Records: []*model.Record{
+		{Name: "www.other.net", Type: model.RT_A, ShortAnswers: []string{"5.6.7.8"}, TTL: 30},
+	}})
+	server.AddZone(&model.Zone{Name: "linked.com", TTL: 3600, Records: []*model.Record{
+		{Name: "www.linked.com", Type: model.RT_A, Link: "www.example.com", Tier: 2, TTL: 120},
+	}})
+	settings := newTestSettings()
+	settings.InventoryOfRecordsFilter = model.NewRegexpOrPanic(`\.com$`)
+	exporter := NewNsoneExporter([]NsoneAccount{newTestAccount(t, server, "a", settings)})
+
+	samples := gather(t, exporter)
+
+	assertSamples(t, samples, map[string]float64{
+		`nsone_collector_success{account="a",collector="record_inventory"}`:                                              1,
+		`nsone_record_info{account="a",link="",record="www.example.com",recordType="A",zone="example.com"}`:              1,
+		`nsone_record_ttl_seconds{account="a",record="www.example.com",recordType="A",zone="example.com"}`:               60,
+		`nsone_record_tier{account="a",record="www.example.com",recordType="A",zone="example.com"}`:                      0,
+		`nsone_record_answers{account="a",record="www.example.com",recordType="A",zone="example.com"}`:                   1,
+		`nsone_record_info{account="a",link="",record="example.com",recordType="MX",zone="example.com"}`:                 1,
+		`nsone_record_info{account="a",link="www.example.com",record="www.linked.com",recordType="A",zone="linked.com"}`: 1,
+		`nsone_record_ttl_seconds{account="a",record="www.linked.com",recordType="A",zone="linked.com"}`:                 120,
+		`nsone_record_tier{account="a",record="www.linked.com",recordType="A",zone="linked.com"}`:                        2,
+		`nsone_record_answers{account="a",record="www.linked.com",recordType="A",zone="linked.com"}`:                     0,
+	})
+	refuteSamples(t, samples,
+		`nsone_record_info{account="a",link="",record="www.other.net"`,
+		`nsone_record_ttl_seconds{account="a",record="www.other.net"`,
+	)
+}
+
+func TestCollectExportsTransferStateOfSecondaryAndPrimaryZones(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	server.AddZone(&model.Zone{Name: "secondary.net", TTL: 3600, Secondary: &model.ZoneSecondary{
+		Enabled:   true,
+		PrimaryIp: "10.0.0.1",
+		OtherIps:  []string{"10.0.0.2"},
+		LastXfr:   1700000000,
+		Status:    "error",
+		Error:     "connection refused",
+	}})
+	server.AddZone(&model.Zone{Name: "primary.net", TTL: 3600, Primary: &model.ZonePrimary{
+		Enabled:     true,
+		Secondaries: []*model.ZoneSecondaryServer{{Ip: "1.1.1.1"}, {Ip: "2.2.2.2"}},
+	}})
+	settings := newTestSettings()
+	settings.InventoryOfZonesFilter = model.NewRegexpOrPanic(`\.net$`)
+	exporter := NewNsoneExporter([]NsoneAccount{newTestAccount(t, server, "a", settings)})
+
+	samples := gather(t, exporter)
+
+	assertSamples(t, samples, map[string]float64{
+		`nsone_zone_secondary_last_xfr_timestamp_seconds{account="a",zone="secondary.net"}`:                            1700000000,
+		`nsone_zone_secondary_xfr_status{account="a",masters="10.0.0.1,10.0.0.2",status="error",zone="secondary.net"}`: 1,
+		`nsone_zone_secondary_error{account="a",zone="secondary.net"}`:                                                 1,
+		`nsone_zone_secondary_expired{account="a",zone="secondary.net"}`:                                               0,
+		`nsone_zone_primary_secondaries{account="a",zone="primary.net"}`:                                               2,
+	})
+	refuteSamples(t, samples,
+		`nsone_zone_secondary_error{account="a",zone="primary.net"}`,
+		`nsone_zone_primary_secondaries{account="a",zone="secondary.net"}`,
+		`nsone_zone_info{account="a",link="",pool="",primary="",zone="example.com"}`,
+	)
+}
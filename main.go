@@ -2,11 +2,14 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"flag"
 	"fmt"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
+	"net/url"
 	"os"
+	"sort"
 	"strings"
 	"time"
 	"github.com/echocat/nsone_exporter/model"
@@ -27,18 +30,35 @@ var (
 		"\tThis should include the whole certificate chain.\n"+
 		"\tIf provided: The web socket will be a HTTPS socket.\n"+
 		"\tIf not provided: Only HTTP.")
+	scrapeTimeoutOffset = flag.Duration("web.scrape-timeout-offset", 500*time.Millisecond, "Offset to subtract from the timeout of a scrape (X-Prometheus-Scrape-Timeout-Seconds header).\n"+
+		"\tFetching the stats from NSONE is cancelled after this reduced timeout and everything complete until then is delivered.")
 	tlsPrivateKey = flag.String("web.tls-private-key", "", "Path to PEM file that contains the private key (if not contained in web.tls-cert file).")
 	tlsClientCa   = flag.String("web.tls-client-ca", "", "Path to PEM file that conains the CAs that are trused for client connections.\n"+
 		"\tIf provided: Connecting clients should present a certificate signed by one of this CAs.\n"+
 		"\tIf not provided: Every client will be accepted.")
 	nsoneToken                         = flag.String("nsone.token", "", "Token to access the API of nsone.")
+	nsoneAccountName                   = flag.String("nsone.account-name", "default", "Name of the account of -nsone.token. Exported as 'account' label of every metric.")
+	nsoneAccounts                      = &accountTokens{}
+	nsoneApiUrl                        = flag.String("nsone.api-url", model.DefaultApiUri, "Base URL of the NSONE v1 API.\n"+
+		"\tChange this to use private/managed DNS deployments that expose the v1 API on an own host.")
+	nsoneCaFiles                       = flag.String("nsone.ca-files", "", "Comma separated paths to PEM files that contains CAs that are trusted in addition to the internal CA bundle.")
+	nsoneProxy                         = flag.String("nsone.proxy", "", "URL of the proxy to access the API of nsone.\n"+
+		"\tIf not provided: The proxy will be taken from the environment (HTTPS_PROXY, NO_PROXY, ...).")
 	nsoneTimeout                       = flag.Duration("nsone.timeout", 5*time.Second, "Timeout for trying to get stats from NSONE.")
 	nsoneNumberOfWorkers               = flag.Int("nsone.workers", 50, "Parallel workers that retreives details from NSONE.")
 	nsoneNumberOfConcurrentConnections = flag.Int("nsone.number-of-concurrent-connections", 50, "Number of concurrent connections to in parallel to NSONE api.")
 
+	configFile = flag.String("config.file", "", "Path to YAML file that describes the accounts and which of their metrics are exported.\n"+
+		"\tFlags that are explicitly provided override the corresponding values of every account in this file.")
+
+	collectInterval = flag.Duration("collect.interval", 0, "Interval to collect stats from NSONE in background.\n"+
+		"\tIf provided: Scrapes only deliver the stats of the latest background collection.\n"+
+		"\tIf not provided: Every scrape collects the stats from NSONE.")
+
 	exportUsageByHourFilter = model.NewRegexpOrPanic("off")
 	exportUsageByDayFilter = model.NewRegexpOrPanic("off")
 	exportUsageByMonthFilter = model.NewRegexpOrPanic(".*")
+	exportUsageGraph = model.UG_OFF
 
 	exportUsageOfAccount = flag.Bool("export.usage-of-account", true, "Export usages of whole account metric.\n" +
 		"\tMetric: 'nsone.usage.account.<period>'")
@@ -50,10 +70,53 @@ var (
 	exportQpsOfZonesFilter = model.NewRegexpOrPanic("off")
 	exportQpsOfRecordsFilter = model.NewRegexpOrPanic("off")
 
+	exportInventoryOfZonesFilter = model.NewRegexpOrPanic("off")
+	exportInventoryOfRecordsFilter = model.NewRegexpOrPanic("off")
+	exportInventoryOfAnswersFilter = model.NewRegexpOrPanic("off")
+	exportLogZoneChanges = flag.Bool("export.log-zone-changes", false, "Log the records that were added, removed or changed if the serial of a zone changes.")
+	exportDriftZoneFiles = &zoneFiles{}
+	exportPulsarFilter = model.NewRegexpOrPanic("off")
+	exportMonitorsFilter = model.NewRegexpOrPanic("off")
+	exportDataFeedsFilter = model.NewRegexpOrPanic("off")
+	exportMonitorMetrics = flag.Bool("export.monitor-metrics", false, "Export the round trip time, connect time and other metrics of the last hour of monitoring jobs by region.\n" +
+		"\tOnly jobs that match -export.monitors-filter are exported.")
+
+	backfillOutput = flag.String("backfill.output", "-", "File to write the OpenMetrics of the 'backfill' command to.\n"+
+		"\tFor stdout: '-'")
+
+	dumpOutput = flag.String("dump.output", "-", "File to write the metrics of the 'dump' command to. It is replaced atomically and contains no timestamps.\n"+
+		"\tFor stdout: '-'\n"+
+		"\tFor the textfile collector of node_exporter: '<directory>/nsone.prom'")
+
+	exportZoneZone = flag.String("export-zone.zone", "", "Zone to write with the 'export-zone' command.")
+	exportZoneAccount = flag.String("export-zone.account", "", "Account of the zone to write with the 'export-zone' command.\n"+
+		"\tCould be omitted if there is only one account.")
+	exportZoneOutput = flag.String("export-zone.output", "-", "File to write the zone of the 'export-zone' command to. It is replaced atomically.\n"+
+		"\tFor stdout: '-'")
+
+	diffZoneName = flag.String("diff.zone", "", "Zone to compare with the 'diff' command.")
+	diffAccount = flag.String("diff.account", "", "Account of the zone to compare with the 'diff' command.\n"+
+		"\tCould be omitted if there is only one account.")
+	diffFile = flag.String("diff.file", "", "BIND zone file or YAML declaration (*.yml, *.yaml) with the expected records for the 'diff' command.")
+
+	command     = ""
 	flagsBuffer = &bytes.Buffer{}
 )
 
+// commands are all available commands in addition to the default which serves the metrics.
+var commands = map[string]string{
+	"backfill": "Writes the complete buckets of the monthly usage graphs as OpenMetrics.\n" +
+		"\tThe result could be imported with 'promtool tsdb create-blocks-from openmetrics'.",
+	"dump": "Collects the stats exactly once and writes them in the text exposition format.\n" +
+		"\tExits with a non-zero code if the collection of any account failed.",
+	"diff": "Compares the records of zone -diff.zone with the expected records of -diff.file.\n" +
+		"\tExits with 1 if there are missing, extra or changed records and with 2 on errors.",
+	"export-zone": "Writes the zone -export-zone.zone with all its records as RFC 1035 master file (BIND zone file).",
+}
+
 func main() {
+	flag.Var(nsoneAccounts, "nsone.account", "Additional account to export in format '<name>=<token>'. Could be provided multiple times.\n"+
+		"\tEvery account uses the same -nsone.* and -export.* settings but own workers.")
 	flag.Var(exportUsageByHourFilter, "export.usage-by-hour-filter", "Export usages by regex of hour metrics.\n" +
 		"\tMetric: 'nsone.usage.<dataPoint>.hourly'\n" +
 		"\tFor disable: 'off'\n" +
@@ -72,6 +135,11 @@ func main() {
 		"\tFor matching account: 'account'\n" +
 		"\tFor matching zone: '<zoneName>'\n" +
 		"\tFor matching record: '<recordType> <recordName>'")
+	flag.Var(&exportUsageGraph, "export.usage-graph", "Export the graph of every exported usage.\n" +
+		"\tMetric: 'nsone.usage.<dataPoint>.<period>.last_bucket'\n" +
+		"\tFor disable: 'off'\n" +
+		"\tFor value of last complete bucket: 'last-bucket'\n" +
+		"\tFor value of last complete bucket with its timestamp: 'timestamps'")
 
 	flag.Var(exportUsageOfZonesFilter, "export.usage-of-zones-filter", "Export usages by regex of zone metrics.\n" +
 		"\tMetric: 'nsone.usage.zones.<period>'\n" +
@@ -91,24 +159,97 @@ func main() {
 		"\tFor disable: 'off'\n" +
 		"\tFor matching record: '<recordType> <recordName>'")
 
+	flag.Var(exportInventoryOfZonesFilter, "export.inventory-of-zones-filter", "Export details (SOA, records by type, zone transfers, ...) by regex of zone metrics.\n" +
+		"\tMetric: 'nsone.zone.<detail>'\n" +
+		"\tFor disable: 'off'\n" +
+		"\tFor matching zone: '<zoneName>'")
+	flag.Var(exportInventoryOfRecordsFilter, "export.inventory-of-records-filter", "Export details (TTL, tier, answers, ...) by regex of record metrics.\n" +
+		"\tMetric: 'nsone.record.<detail>'\n" +
+		"\tFor disable: 'off'\n" +
+		"\tFor matching record: '<recordType> <recordName>'")
+	flag.Var(exportInventoryOfAnswersFilter, "export.inventory-of-answers-filter", "Export metadata (up, weight, priority) of answers and the filter chain by regex of records.\n" +
+		"\tMetric: 'nsone.answer.<detail>', 'nsone.record.filter.info'\n" +
+		"\tFor disable: 'off'\n" +
+		"\tFor matching record: '<recordType> <recordName>'")
+	flag.Var(exportDriftZoneFiles, "export.drift-zone-file", "Compare the records of a zone with a file in format '<zoneName>=<file>'. Could be provided multiple times.\n" +
+		"\tMetric: 'nsone.zone.drift.records'\n" +
+		"\tThe file is either a BIND zone file or a YAML declaration (*.yml, *.yaml) and read on every collection.")
+	flag.Var(exportPulsarFilter, "export.pulsar-filter", "Export information (type, active), latency and availability by geo region of Pulsar jobs by regex.\n" +
+		"\tMetric: 'nsone.pulsar.job.<detail>'\n" +
+		"\tFor disable: 'off'\n" +
+		"\tFor matching job: '<appName> <jobName>'")
+	flag.Var(exportMonitorsFilter, "export.monitors-filter", "Export status and frequency of monitoring jobs by regex.\n" +
+		"\tMetric: 'nsone.monitor.<detail>'\n" +
+		"\tFor disable: 'off'\n" +
+		"\tFor matching job: '<jobName>'")
+	flag.Var(exportDataFeedsFilter, "export.data-feeds-filter", "Export metadata (up, weight, ...) of data feeds and the records they are applied to by regex.\n" +
+		"\tMetric: 'nsone.datafeed.<detail>'\n" +
+		"\tFor disable: 'off'\n" +
+		"\tFor matching feed: '<sourceName> <feedName>'")
+
 	parseUsage()
 
-	exporter := NewNsoneExporter(*nsoneToken, *nsoneTimeout, *nsoneNumberOfWorkers, *nsoneNumberOfConcurrentConnections, NsoneExportSettings{
-		UsageByHourFilter:  exportUsageByHourFilter,
-		UsageByDayFilter:   exportUsageByDayFilter,
-		UsageByMonthFilter: exportUsageByMonthFilter,
+	accounts, err := createAccounts()
+	if err != nil {
+		fail(err)
+	}
 
-		UsageOfAccount: *exportUsageOfAccount,
-		UsageOfZonesFilter:   exportUsageOfZonesFilter,
-		UsageOfRecordsFilter: exportUsageOfRecordsFilter,
+	switch command {
+	case "backfill":
+		err = backfill(context.Background(), accounts, *backfillOutput)
+		if err != nil {
+			log.Fatalf("Could not backfill. Cause: %v", err)
+		}
+		return
+	case "diff":
+		if len(*diffZoneName) <= 0 || len(*diffFile) <= 0 {
+			fail("Missing -diff.zone or -diff.file.")
+		}
+		account, err := selectAccount(accounts, *diffAccount)
+		if err != nil {
+			fail(err)
+		}
+		drift, err := diffZone(context.Background(), account, *diffZoneName, *diffFile, os.Stdout)
+		if err != nil {
+			log.Errorf("Could not compare zone %s. Cause: %v", *diffZoneName, err)
+			os.Exit(2)
+		}
+		if drift {
+			os.Exit(1)
+		}
+		return
+	case "export-zone":
+		if len(*exportZoneZone) <= 0 {
+			fail("Missing -export-zone.zone.")
+		}
+		account, err := selectAccount(accounts, *exportZoneAccount)
+		if err != nil {
+			fail(err)
+		}
+		err = exportZone(context.Background(), account, *exportZoneZone, *exportZoneOutput)
+		if err != nil {
+			log.Fatalf("Could not export zone %s. Cause: %v", *exportZoneZone, err)
+		}
+		return
+	}
 
-		QpsOfAccount: *exportQpsOfAccount,
-		QpsOfZonesFilter:   exportQpsOfZonesFilter,
-		QpsOfRecordsFilter: exportQpsOfRecordsFilter,
-	})
-	prometheus.MustRegister(exporter)
+	exporter := NewNsoneExporter(accounts)
+	if command == "dump" {
+		err = dump(exporter, *dumpOutput)
+		if err != nil {
+			log.Fatalf("Could not dump. Cause: %v", err)
+		}
+		return
+	}
+	if *collectInterval > 0 {
+		exporter.StartCollectingEvery(*collectInterval)
+	}
+	reloader := newConfigReloader(exporter)
+	prometheus.MustRegister(reloader)
+	reloader.ReloadOnSignal()
 
-	err := startServer(*metricsPath, *listenAddress, *tlsCert, *tlsPrivateKey, *tlsClientCa)
+	metrics := newMetricsHandler(exporter, *scrapeTimeoutOffset)
+	err = startServer(*metricsPath, *listenAddress, *tlsCert, *tlsPrivateKey, *tlsClientCa, metrics, reloader.Reload)
 	if err != nil {
 		log.Fatalf("Could not start server. Cause: %v", err)
 	}
@@ -125,19 +266,77 @@ func parseUsage() {
 			printUsage(nil)
 		}
 	}
-	flags.Parse(os.Args[1:])
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command = args[0]
+		args = args[1:]
+	}
+	flags.Parse(args)
 	assertUsage()
 }
 
 func assertUsage() {
+	if _, ok := commands[command]; len(command) > 0 && !ok {
+		fail(fmt.Sprintf("Unknown command: %s", command))
+	}
+	if len(flag.Args()) > 0 {
+		fail(fmt.Sprintf("Unexpected arguments: %s", strings.Join(flag.Args(), " ")))
+	}
 	if len(strings.TrimSpace(*listenAddress)) == 0 {
 		fail("Missing -web.listen-address")
 	}
-	if len(strings.TrimSpace(*nsoneToken)) == 0 {
-		fail("Missing -nsone.token")
+	if len(strings.TrimSpace(*nsoneToken)) == 0 && len(*nsoneAccounts) == 0 && len(*configFile) == 0 {
+		fail("Missing -nsone.token, -nsone.account or -config.file")
+	}
+	if len(strings.TrimSpace(*nsoneToken)) > 0 {
+		if err := nsoneAccounts.assertNameAvailable(*nsoneAccountName); err != nil {
+			fail(fmt.Sprintf("Illegal -nsone.account-name: %v", err))
+		}
+	}
+	if *collectInterval < 0 {
+		fail("Illegal -collect.interval: Could not be negative")
+	}
+	if err := assertApiUrl(*nsoneApiUrl); err != nil {
+		fail(fmt.Sprintf("Illegal -nsone.api-url: %v", err))
+	}
+	if len(*nsoneProxy) > 0 {
+		if proxyUrl, err := url.Parse(*nsoneProxy); err != nil || len(proxyUrl.Host) <= 0 {
+			fail(fmt.Sprintf("Illegal -nsone.proxy: %s", *nsoneProxy))
+		}
 	}
 }
 
+func assertApiUrl(plain string) error {
+	apiUrl, err := url.Parse(plain)
+	if err != nil {
+		return err
+	}
+	if apiUrl.Scheme != "https" && apiUrl.Scheme != "http" {
+		return fmt.Errorf("Scheme have to be either 'https' or 'http' but got: '%s'", apiUrl.Scheme)
+	}
+	if len(apiUrl.Host) <= 0 {
+		return fmt.Errorf("No host provided in '%s'", plain)
+	}
+	if apiUrl.User != nil || len(apiUrl.RawQuery) > 0 || len(apiUrl.Fragment) > 0 {
+		return fmt.Errorf("Neither user information, query nor fragment are allowed in '%s'", plain)
+	}
+	if !strings.HasSuffix(strings.TrimSuffix(apiUrl.Path, "/"), "/v1") {
+		return fmt.Errorf("Path have to point to the v1 API (ends with '/v1') but got: '%s'", apiUrl.Path)
+	}
+	return nil
+}
+
+func splitList(plain string) []string {
+	result := []string{}
+	for _, element := range strings.Split(plain, ",") {
+		trimmed := strings.TrimSpace(element)
+		if len(trimmed) > 0 {
+			result = append(result, trimmed)
+		}
+	}
+	return result
+}
+
 func fail(err interface{}) {
 	printUsage(err)
 	os.Exit(1)
@@ -155,9 +354,96 @@ func printUsage(err interface{}) {
 		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
 	}
 
-	fmt.Fprintf(os.Stderr, "Usage: %v <flags>\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "Usage: %v [command] <flags>\n", os.Args[0])
+	fmt.Fprint(os.Stderr, "Commands:\n")
+	commandNames := []string{}
+	for commandName := range commands {
+		commandNames = append(commandNames, commandName)
+	}
+	sort.Strings(commandNames)
+	for _, commandName := range commandNames {
+		fmt.Fprintf(os.Stderr, "  %s\n\t%s\n", commandName, commands[commandName])
+	}
 	fmt.Fprint(os.Stderr, "Flags:\n")
 	flag.CommandLine.SetOutput(os.Stderr)
 	flag.CommandLine.PrintDefaults()
 	flag.CommandLine.SetOutput(flagsBuffer)
 }
+
+type accountToken struct {
+	name  string
+	token string
+}
+
+// accountTokens collects the accounts of multiple -nsone.account flags.
+type accountTokens []accountToken
+
+func (instance accountTokens) String() string {
+	names := []string{}
+	for _, account := range instance {
+		names = append(names, account.name+"=***")
+	}
+	return strings.Join(names, ",")
+}
+
+// Set adds an account and checks for potential errors.
+func (instance *accountTokens) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || len(strings.TrimSpace(parts[1])) <= 0 {
+		return fmt.Errorf("Expected format '<name>=<token>' but got: '%s'", value)
+	}
+	name := strings.TrimSpace(parts[0])
+	if err := instance.assertNameAvailable(name); err != nil {
+		return err
+	}
+	(*instance) = append(*instance, accountToken{
+		name:  name,
+		token: strings.TrimSpace(parts[1]),
+	})
+	return nil
+}
+
+func (instance accountTokens) assertNameAvailable(name string) error {
+	if len(name) <= 0 {
+		return fmt.Errorf("Empty account name is not allowed.")
+	}
+	for _, account := range instance {
+		if account.name == name {
+			return fmt.Errorf("Account '%s' was already provided.", name)
+		}
+	}
+	return nil
+}
+
+func (instance accountTokens) withDefault(name string, token string) accountTokens {
+	if len(strings.TrimSpace(token)) <= 0 {
+		return instance
+	}
+	return append(accountTokens{{name: name, token: token}}, instance...)
+}
+
+// zoneFiles collects the files by zone of multiple -export.drift-zone-file flags.
+type zoneFiles map[string]string
+
+func (instance zoneFiles) String() string {
+	result := []string{}
+	for zone, file := range instance {
+		result = append(result, zone+"="+file)
+	}
+	sort.Strings(result)
+	return strings.Join(result, ",")
+}
+
+// Set adds a file of a zone and checks for potential errors.
+func (instance *zoneFiles) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || len(strings.TrimSpace(parts[0])) <= 0 || len(strings.TrimSpace(parts[1])) <= 0 {
+		return fmt.Errorf("Expected format '<zoneName>=<file>' but got: '%s'", value)
+	}
+	zone := strings.TrimSpace(parts[0])
+	if _, ok := (*instance)[zone]; ok {
+		return fmt.Errorf("Zone '%s' was already provided.", zone)
+	}
+	(*instance)[zone] = strings.TrimSpace(parts[1])
+	return nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAssertApiUrlAcceptsUrlsOfTheV1Api(t *testing.T) {
+	for _, candidate := range []string{
+		"https://api.nsone.net/v1",
+		"https://api.nsone.net/v1/",
+		"http://dns.example.com:8080/managed/v1",
+	} {
+		if err := assertApiUrl(candidate); err != nil {
+			t.Errorf("Expected %s to be accepted but got: %v", candidate, err)
+		}
+	}
+}
+
+func TestAssertApiUrlRejectsIllegalUrls(t *testing.T) {
+	cases := map[string]string{
+		"api.nsone.net/v1":                 "Scheme have to be either 'https' or 'http'",
+		"ftp://api.nsone.net/v1":           "Scheme have to be either 'https' or 'http'",
+		"https:///v1":                      "No host provided",
+		"https://api.nsone.net":            "Path have to point to the v1 API",
+		"https://api.nsone.net/v2/":        "Path have to point to the v1 API",
+		"https://api.nsone.net/v1?debug=1": "Neither user information, query nor fragment are allowed",
+		"https://user@api.nsone.net/v1":    "Neither user information, query nor fragment are allowed",
+	}
+	for candidate, expected := range cases {
+		if err := assertApiUrl(candidate); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error containing '%s' for %s but got: %v", expected, candidate, err)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+package model
+
+// Answer is one answer of a Record. Answer contains the rdata elements, for example
+// ["10", "mx.example.com"] for a MX record.
+type Answer struct {
+	Id     string   `json:"id"`
+	Answer []string `json:"answer"`
+	// Region is the name of the region (see Record.Regions) this answer belongs to.
+	Region string `json:"region"`
+	Meta   *Meta  `json:"meta"`
+}
@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+)
+
+// ClientObserver is notified about every request a Client executes against the NSONE API.
+// The endpoint is templated (like '/zones/{zone}') to keep the number of endpoints small.
+type ClientObserver interface {
+	// ObserveRequest is called after every attempt of a request. statusCode is 0 if no
+	// response was received at all.
+	ObserveRequest(endpoint string, statusCode int, duration time.Duration)
+	// ObserveRetry is called every time before a request is retried.
+	ObserveRetry(endpoint string)
+}
+
+type noopClientObserver struct{}
+
+func (instance noopClientObserver) ObserveRequest(endpoint string, statusCode int, duration time.Duration) {
+}
+
+func (instance noopClientObserver) ObserveRetry(endpoint string) {}
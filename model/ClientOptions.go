@@ -0,0 +1,69 @@
+package model
+
+import (
+	"crypto/tls"
+	"fmt"
+	"github.com/echocat/nsone_exporter/utils"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ClientOptions contains everything that is required to create a new Client.
+type ClientOptions struct {
+	// ApiUri is the base URI of the NSONE v1 API. If empty DefaultApiUri will be used.
+	ApiUri                               string
+	AccessToken                          string
+	Timeout                              time.Duration
+	MaximumNumberOfConcurrentConnections int
+
+	// Transport replaces the whole default transport. If set Timeout, CaFiles and Proxy
+	// are ignored and the transport is responsible for these by itself.
+	Transport http.RoundTripper
+	// CaFiles are PEM files of CAs that are trusted in addition to the internal CA bundle.
+	CaFiles []string
+	// Proxy is the URI of the proxy to use. If empty the proxy is taken from the environment.
+	Proxy string
+	// Observer is notified about every request. Optional.
+	Observer ClientObserver
+}
+
+func (instance ClientOptions) transport() (http.RoundTripper, error) {
+	if instance.Transport != nil {
+		return instance.Transport, nil
+	}
+	certificates := utils.LoadInternalCaBunlde()
+	for _, caFile := range instance.CaFiles {
+		err := utils.AppendCertificatesFrom(certificates, caFile)
+		if err != nil {
+			return nil, fmt.Errorf("Could not load CAs from %s. Cause: %v", caFile, err)
+		}
+	}
+	proxy := http.ProxyFromEnvironment
+	if len(instance.Proxy) > 0 {
+		proxyUri, err := url.Parse(instance.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("Illegal proxy uri: %s. Cause: %v", instance.Proxy, err)
+		}
+		proxy = http.ProxyURL(proxyUri)
+	}
+	timeout := instance.Timeout
+	return &http.Transport{
+		Proxy:               proxy,
+		MaxIdleConnsPerHost: 100,
+		TLSClientConfig: &tls.Config{
+			RootCAs: certificates,
+		},
+		Dial: func(netw, addr string) (net.Conn, error) {
+			c, err := net.DialTimeout(netw, addr, timeout)
+			if err != nil {
+				return nil, err
+			}
+			if err := c.SetDeadline(time.Now().Add(timeout)); err != nil {
+				return nil, err
+			}
+			return c, nil
+		},
+	}, nil
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"strconv"
+)
+
+// DataFeed is a feed of a DataSource that carries metadata (like up and weight) which is
+// applied to the destinations of the feed.
+type DataFeed struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	// Data is the latest metadata NSONE received, for example {"up": true, "weight": 10}.
+	Data         map[string]interface{} `json:"data"`
+	Destinations []*DataFeedDestination `json:"destinations"`
+}
+
+// NumericData returns the value of the metadata with the given name as number. Booleans are
+// converted to 0 or 1. false is returned if there is no such metadata or it is not numeric.
+func (instance DataFeed) NumericData(name string) (float64, bool) {
+	switch value := instance.Data[name].(type) {
+	case float64:
+		return value, true
+	case bool:
+		if value {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return parsed, true
+		}
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			if parsed {
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,16 @@
+package model
+
+const (
+	DD_ANSWER = "answer"
+	DD_RECORD = "record"
+	DD_REGION = "region"
+)
+
+// DataFeedDestination references the record, answer or region a DataFeed is applied to.
+type DataFeedDestination struct {
+	// Id is the id of the answer or region. It is the id of the record if Type is 'record'.
+	Id   string `json:"destid"`
+	Type string `json:"desttype"`
+	// Record is the id of the record that contains the destination.
+	Record string `json:"record"`
+}
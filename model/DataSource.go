@@ -0,0 +1,10 @@
+package model
+
+// DataSource is a source of NSONE data feeds, for example a monitoring service that
+// pushes the state of endpoints.
+type DataSource struct {
+	Id     string `json:"id"`
+	Name   string `json:"name"`
+	Type   string `json:"sourcetype"`
+	Status string `json:"status"`
+}
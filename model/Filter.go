@@ -0,0 +1,81 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Filter is a Matcher that matches everything that matches at least one of its includes
+// (or everything if there are no includes) but none of its excludes.
+type Filter struct {
+	enabled  bool
+	includes []*regexp.Regexp
+	excludes []*regexp.Regexp
+}
+
+// NewFilter creates a new enabled Filter from the given include and exclude patterns.
+func NewFilter(includes []string, excludes []string) (*Filter, error) {
+	result := &Filter{
+		enabled: true,
+	}
+	for _, include := range includes {
+		pattern, err := regexp.Compile(include)
+		if err != nil {
+			return nil, fmt.Errorf("Illegal include regexp: %v. Got: %v", include, err)
+		}
+		result.includes = append(result.includes, pattern)
+	}
+	for _, exclude := range excludes {
+		pattern, err := regexp.Compile(exclude)
+		if err != nil {
+			return nil, fmt.Errorf("Illegal exclude regexp: %v. Got: %v", exclude, err)
+		}
+		result.excludes = append(result.excludes, pattern)
+	}
+	return result, nil
+}
+
+// NewDisabledFilter creates a new Filter that never matches anything.
+func NewDisabledFilter() *Filter {
+	return &Filter{}
+}
+
+func (instance *Filter) HasValue() bool {
+	return instance != nil && instance.enabled
+}
+
+func (instance *Filter) MatchString(what string) bool {
+	if !instance.HasValue() {
+		return false
+	}
+	for _, exclude := range instance.excludes {
+		if exclude.MatchString(what) {
+			return false
+		}
+	}
+	if len(instance.includes) <= 0 {
+		return true
+	}
+	for _, include := range instance.includes {
+		if include.MatchString(what) {
+			return true
+		}
+	}
+	return false
+}
+
+func (instance Filter) String() string {
+	if !instance.enabled {
+		return "off"
+	}
+	includes := []string{}
+	for _, include := range instance.includes {
+		includes = append(includes, include.String())
+	}
+	excludes := []string{}
+	for _, exclude := range instance.excludes {
+		excludes = append(excludes, exclude.String())
+	}
+	return fmt.Sprintf("include=[%s] exclude=[%s]", strings.Join(includes, ", "), strings.Join(excludes, ", "))
+}
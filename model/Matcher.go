@@ -0,0 +1,11 @@
+package model
+
+// Matcher decides if something (an account, a zone or a record) should be handled.
+type Matcher interface {
+	// HasValue returns false if the matcher is disabled and will never match anything.
+	HasValue() bool
+	MatchString(what string) bool
+	// String returns the definition of the matcher ('off' if it is disabled). Matchers with
+	// the same definition match exactly the same.
+	String() string
+}
@@ -0,0 +1,10 @@
+package model
+
+// Meta is the metadata of answers, regions or records which is used by the filters of a
+// record to select answers.
+type Meta struct {
+	Up        *MetaValue `json:"up,omitempty"`
+	Weight    *MetaValue `json:"weight,omitempty"`
+	Priority  *MetaValue `json:"priority,omitempty"`
+	Georegion *MetaValue `json:"georegion,omitempty"`
+}
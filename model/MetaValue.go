@@ -0,0 +1,83 @@
+package model
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// MetaValue is the value of a metadata of answers, regions or records. It is either a
+// static value (like true, 10 or ["US-EAST"]) or a reference to a DataFeed which provides
+// the value.
+type MetaValue struct {
+	Value interface{}
+	// Feed is the id of the DataFeed which provides the value. Value is nil in this case.
+	Feed string
+}
+
+// Number returns the value as number. Booleans are converted to 0 or 1. false is returned
+// if the value is provided by a feed or not numeric.
+func (instance *MetaValue) Number() (float64, bool) {
+	if instance == nil {
+		return 0, false
+	}
+	switch value := instance.Value.(type) {
+	case float64:
+		return value, true
+	case bool:
+		if value {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return parsed, true
+		}
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			if parsed {
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	return 0, false
+}
+
+// Strings returns the value as list of strings, for example the regions of georegion.
+func (instance *MetaValue) Strings() []string {
+	if instance == nil {
+		return nil
+	}
+	switch value := instance.Value.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		result := []string{}
+		for _, element := range value {
+			if str, ok := element.(string); ok {
+				result = append(result, str)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+func (instance *MetaValue) UnmarshalJSON(b []byte) error {
+	var feed struct {
+		Feed string `json:"feed"`
+	}
+	if err := json.Unmarshal(b, &feed); err == nil && len(feed.Feed) > 0 {
+		instance.Value = nil
+		instance.Feed = feed.Feed
+		return nil
+	}
+	instance.Feed = ""
+	return json.Unmarshal(b, &instance.Value)
+}
+
+func (instance MetaValue) MarshalJSON() ([]byte, error) {
+	if len(instance.Feed) > 0 {
+		return json.Marshal(map[string]string{"feed": instance.Feed})
+	}
+	return json.Marshal(instance.Value)
+}
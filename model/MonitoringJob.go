@@ -0,0 +1,15 @@
+package model
+
+// MonitoringJob is a health check of NSONE that monitors an endpoint from multiple regions.
+type MonitoringJob struct {
+	Id      string `json:"id"`
+	Name    string `json:"name"`
+	JobType string `json:"job_type"`
+	Active  bool   `json:"active"`
+	// Frequency is the interval of the checks in seconds.
+	Frequency int      `json:"frequency"`
+	Regions   []string `json:"regions"`
+	// Policy describes how the status of the regions is combined to the global status. Possible values: quorum, one and all
+	Policy string                `json:"policy"`
+	Status MonitoringJobStatuses `json:"status"`
+}
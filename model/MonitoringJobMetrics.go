@@ -0,0 +1,13 @@
+package model
+
+const (
+	MM_RTT     = "rtt"
+	MM_CONNECT = "connect"
+)
+
+// MonitoringJobMetrics are the metrics of one MonitoringJob by region and by metric name
+// (like rtt and connect).
+type MonitoringJobMetrics struct {
+	JobId   string                                  `json:"jobid"`
+	Metrics map[string]map[string]*MonitoringMetric `json:"metrics"`
+}
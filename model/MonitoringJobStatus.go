@@ -0,0 +1,19 @@
+package model
+
+const (
+	MS_UP      = "up"
+	MS_DOWN    = "down"
+	MS_PENDING = "pending"
+)
+
+// MonitoringJobStatus is the status of a MonitoringJob in one region.
+type MonitoringJobStatus struct {
+	// Since is the unix timestamp of the last change of the status.
+	Since  int64  `json:"since"`
+	Status string `json:"status"`
+}
+
+// IsUp returns true if the job is up in this region.
+func (instance MonitoringJobStatus) IsUp() bool {
+	return instance.Status == MS_UP
+}
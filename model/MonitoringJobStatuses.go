@@ -0,0 +1,5 @@
+package model
+
+// MonitoringJobStatuses contains the status of a MonitoringJob by region. The combined
+// status of all regions is contained as region 'global'.
+type MonitoringJobStatuses map[string]*MonitoringJobStatus
@@ -0,0 +1,8 @@
+package model
+
+// MonitoringMetric is one metric (like rtt) of a MonitoringJob in one region over a period.
+type MonitoringMetric struct {
+	// Avg is the average of the metric over the period. Times are in milliseconds.
+	Avg   float64     `json:"avg"`
+	Graph [][]float64 `json:"graph"`
+}
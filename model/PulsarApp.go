@@ -0,0 +1,8 @@
+package model
+
+// PulsarApp is an application of NSONE Pulsar (real user monitoring) which groups jobs.
+type PulsarApp struct {
+	Id     string `json:"appid"`
+	Name   string `json:"name"`
+	Active bool   `json:"active"`
+}
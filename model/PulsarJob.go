@@ -0,0 +1,13 @@
+package model
+
+// PulsarJob is a job of a PulsarApp that measures one endpoint (like a CDN) from the
+// browsers of real users.
+type PulsarJob struct {
+	Id    string `json:"jobid"`
+	AppId string `json:"appid"`
+	Name  string `json:"name"`
+	// Type is the kind of measurement. Possible values: latency, availability and custom
+	Type      string `json:"typeid"`
+	Active    bool   `json:"active"`
+	Community bool   `json:"community"`
+}
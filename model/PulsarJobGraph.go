@@ -0,0 +1,24 @@
+package model
+
+// PL_ALL is the key of the series over all geo regions or all ASNs of a PulsarJobGraph.
+const PL_ALL = "*"
+
+// PulsarJobGraph is the performance (latency in milliseconds) or availability (ratio 0..1)
+// of a PulsarJob by geo region and by ASN. Every series contains [timestamp, value] points.
+type PulsarJobGraph struct {
+	Graph map[string]map[string][][]float64 `json:"graph"`
+}
+
+// LastValuesByGeo returns the last value of the series over all ASNs of every geo region.
+// Geo regions without such a value are not contained.
+func (instance PulsarJobGraph) LastValuesByGeo() map[string]float64 {
+	result := map[string]float64{}
+	for geo, byAsn := range instance.Graph {
+		series := byAsn[PL_ALL]
+		if len(series) <= 0 || len(series[len(series)-1]) < 2 {
+			continue
+		}
+		result[geo] = series[len(series)-1][1]
+	}
+	return result
+}
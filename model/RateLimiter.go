@@ -0,0 +1,139 @@
+package model
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimiter paces requests against the NSONE API. It learns the limits from the
+// X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Period headers of every
+// response and lets callers wait before the limit is exceeded. It is shared by all
+// requests of one Client.
+type RateLimiter struct {
+	lock        sync.Mutex
+	limit       int
+	period      time.Duration
+	tokens      float64
+	lastRefill  time.Time
+	throttled   uint64
+	rateLimited uint64
+}
+
+// RateLimiterStats is the current state of a RateLimiter.
+type RateLimiterStats struct {
+	// Known is true if the limits are known from a previous response.
+	Known bool
+	// Limit is the number of requests that are allowed per Period.
+	Limit  int
+	Period time.Duration
+	// Remaining is the estimated number of requests that could be executed without waiting.
+	Remaining float64
+	// Throttled is the number of requests that were delayed by the RateLimiter.
+	Throttled uint64
+	// RateLimited is the number of requests that were rejected by NSONE with 429.
+	RateLimited uint64
+}
+
+// NewRateLimiter creates a new RateLimiter that does not delay any request until it
+// learned the limits with Update.
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{}
+}
+
+// Wait blocks until the next request could be executed without exceeding the limit or
+// until ctx is done.
+func (instance *RateLimiter) Wait(ctx context.Context) error {
+	instance.lock.Lock()
+	if instance.limit <= 0 {
+		instance.lock.Unlock()
+		return nil
+	}
+	instance.refill(time.Now())
+	var delay time.Duration
+	if instance.tokens < 1 {
+		delay = time.Duration((1 - instance.tokens) * float64(instance.period) / float64(instance.limit))
+		instance.throttled++
+	}
+	// Tokens could become negative. So every further caller waits for its own token.
+	instance.tokens--
+	instance.lock.Unlock()
+	if delay > 0 {
+		if err := sleep(ctx, delay); err != nil {
+			instance.lock.Lock()
+			instance.tokens++
+			instance.lock.Unlock()
+			return err
+		}
+	}
+	return nil
+}
+
+// Update learns the limits from the headers of the given response.
+func (instance *RateLimiter) Update(response *http.Response) {
+	limit, lErr := strconv.Atoi(response.Header.Get("X-RateLimit-Limit"))
+	remaining, rErr := strconv.Atoi(response.Header.Get("X-RateLimit-Remaining"))
+	period, pErr := strconv.Atoi(response.Header.Get("X-RateLimit-Period"))
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	if response.StatusCode == http.StatusTooManyRequests {
+		instance.rateLimited++
+		if instance.tokens > 0 {
+			instance.tokens = 0
+		}
+	}
+	if lErr != nil || rErr != nil || pErr != nil || limit <= 0 || period <= 0 {
+		return
+	}
+	now := time.Now()
+	if instance.limit <= 0 {
+		instance.tokens = float64(remaining)
+		instance.lastRefill = now
+	}
+	instance.limit = limit
+	instance.period = time.Duration(period) * time.Second
+	instance.refill(now)
+	if float64(remaining) < instance.tokens {
+		instance.tokens = float64(remaining)
+	}
+}
+
+// Knows returns true if the limits are known from a previous response.
+func (instance *RateLimiter) Knows() bool {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	return instance.limit > 0
+}
+
+// Stats returns the current state of this RateLimiter.
+func (instance *RateLimiter) Stats() RateLimiterStats {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	result := RateLimiterStats{
+		Known:       instance.limit > 0,
+		Limit:       instance.limit,
+		Period:      instance.period,
+		Throttled:   instance.throttled,
+		RateLimited: instance.rateLimited,
+	}
+	if result.Known {
+		instance.refill(time.Now())
+		if instance.tokens > 0 {
+			result.Remaining = instance.tokens
+		}
+	}
+	return result
+}
+
+func (instance *RateLimiter) refill(now time.Time) {
+	elapsed := now.Sub(instance.lastRefill)
+	if elapsed > 0 {
+		instance.tokens += float64(elapsed) * float64(instance.limit) / float64(instance.period)
+		if instance.tokens > float64(instance.limit) {
+			instance.tokens = float64(instance.limit)
+		}
+		instance.lastRefill = now
+	}
+}
@@ -8,4 +8,10 @@ type Record struct {
 	Link         string     `json:"link"`
 	TTL          int        `json:"ttl"`
 	Tier         int        `json:"tier"`
+	// Answers are only provided if the record was retrieved by itself (see Client.GetRecord).
+	Answers []*Answer `json:"answers"`
+	// Regions, Meta and Filters are also only provided if the record was retrieved by itself.
+	Regions Regions         `json:"regions"`
+	Meta    *Meta           `json:"meta"`
+	Filters []*RecordFilter `json:"filters"`
 }
@@ -0,0 +1,8 @@
+package model
+
+// RecordFilter is one step of the filter chain of a Record, for example 'up' or 'shuffle'.
+type RecordFilter struct {
+	Filter   string                 `json:"filter"`
+	Disabled bool                   `json:"disabled"`
+	Config   map[string]interface{} `json:"config"`
+}
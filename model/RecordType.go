@@ -6,6 +6,8 @@ import (
 	"strings"
 )
 
+// RecordType is the type of a record. Types that are not contained in AllRecordTypes are
+// kept as they are to not fail if NSONE supports new types.
 type RecordType string
 
 const (
@@ -15,9 +17,13 @@ const (
 	RT_ALIAS    RecordType = "ALIAS"
 	RT_AFSDB    RecordType = "AFSDB"
 	RT_ANY      RecordType = "ANY"
+	RT_CAA      RecordType = "CAA"
+	RT_CERT     RecordType = "CERT"
 	RT_CNAME    RecordType = "CNAME"
 	RT_DNAME    RecordType = "DNAME"
+	RT_DS       RecordType = "DS"
 	RT_HINFO    RecordType = "HINFO"
+	RT_HTTPS    RecordType = "HTTPS"
 	RT_EBOT     RecordType = "EBOT"
 	RT_LINKED   RecordType = "LINKED"
 	RT_MX       RecordType = "MX"
@@ -29,10 +35,14 @@ const (
 	RT_SPF      RecordType = "SPF"
 	RT_SRV      RecordType = "SRV"
 	RT_SOA      RecordType = "SOA"
+	RT_SSHFP    RecordType = "SSHFP"
+	RT_SVCB     RecordType = "SVCB"
+	RT_TLSA     RecordType = "TLSA"
 	RT_TXT      RecordType = "TXT"
+	RT_URLFWD   RecordType = "URLFWD"
 )
 
-// AllRecordTypes contains all possible variants of RecordType.
+// AllRecordTypes contains all known variants of RecordType.
 var AllRecordTypes = []RecordType{
 	RT_NONE,
 	RT_A,
@@ -40,9 +50,13 @@ var AllRecordTypes = []RecordType{
 	RT_ALIAS,
 	RT_AFSDB,
 	RT_ANY,
+	RT_CAA,
+	RT_CERT,
 	RT_CNAME,
 	RT_DNAME,
+	RT_DS,
 	RT_HINFO,
+	RT_HTTPS,
 	RT_EBOT,
 	RT_LINKED,
 	RT_MX,
@@ -54,47 +68,46 @@ var AllRecordTypes = []RecordType{
 	RT_SPF,
 	RT_SRV,
 	RT_SOA,
+	RT_SSHFP,
+	RT_SVCB,
+	RT_TLSA,
 	RT_TXT,
+	RT_URLFWD,
 }
 
 func (instance RecordType) String() string {
-	s, err := instance.CheckedString()
-	if err != nil {
-		panic(err)
+	return string(instance)
+}
+
+// IsKnown returns true if this type is contained in AllRecordTypes.
+func (instance RecordType) IsKnown() bool {
+	for _, candidate := range AllRecordTypes {
+		if candidate == instance {
+			return true
+		}
 	}
-	return s
+	return false
 }
 
 // CheckedString is like String but return also an optional error if there are some
 // validation errors.
 func (instance RecordType) CheckedString() (string, error) {
-	for _, candidate := range AllRecordTypes {
-		if candidate == instance {
-			return string(instance), nil
-		}
+	if !instance.IsKnown() {
+		return string(instance), fmt.Errorf("Unknown record type: %s", string(instance))
 	}
-	return "", fmt.Errorf("Illegal record type: %d", instance)
+	return string(instance), nil
 }
 
-// Set sets the value and checks for potential errors.
+// Set sets the value. Types that are not contained in AllRecordTypes are accepted as they
+// are (see IsKnown).
 func (instance *RecordType) Set(value string) error {
-	upperValue := strings.ToUpper(value)
-	for _, candidate := range AllRecordTypes {
-		if candidate.String() == upperValue {
-			(*instance) = candidate
-			return nil
-		}
-	}
-	return fmt.Errorf("Illegal record type: %v" + value)
+	(*instance) = RecordType(strings.ToUpper(value))
+	return nil
 }
 
 // MarshalJSON is used until json marshalling. Do not call directly.
 func (instance RecordType) MarshalJSON() ([]byte, error) {
-	s, err := instance.CheckedString()
-	if err != nil {
-		return []byte{}, err
-	}
-	return json.Marshal(s)
+	return json.Marshal(string(instance))
 }
 
 // UnmarshalJSON is used until json unmarshalling. Do not call directly.
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRecordTypeKeepsUnknownTypes(t *testing.T) {
+	for _, value := range []string{"TYPE65534", "x.y", "A_B", "CAA"} {
+		var record Record
+		if err := json.Unmarshal([]byte(`{"domain":"www.example.com","type":"`+value+`"}`), &record); err != nil {
+			t.Errorf("Expected %s to be accepted but got: %v", value, err)
+		}
+		marshalled, err := json.Marshal(record.Type)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var unmarshalled RecordType
+		if err := json.Unmarshal(marshalled, &unmarshalled); err != nil || unmarshalled != record.Type {
+			t.Errorf("Expected %s to survive a round trip but got %s (error: %v).", record.Type, unmarshalled, err)
+		}
+	}
+}
+
+func TestRecordTypeIsKnown(t *testing.T) {
+	var recordType RecordType
+	if err := recordType.Set("svcb"); err != nil || recordType != RT_SVCB || !recordType.IsKnown() {
+		t.Errorf("Expected svcb to be the known type SVCB but got %s (error: %v).", recordType, err)
+	}
+	if RecordType("TYPE65534").IsKnown() {
+		t.Error("Expected TYPE65534 to be unknown.")
+	}
+	if _, err := RecordType("TYPE65534").CheckedString(); err == nil {
+		t.Error("Expected an error for the unknown type TYPE65534 but got none.")
+	}
+}
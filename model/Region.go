@@ -0,0 +1,7 @@
+package model
+
+// Region is a named group of answers of a Record. The meta of a region applies to every
+// answer of it which does not define the metadata by itself.
+type Region struct {
+	Meta *Meta `json:"meta"`
+}
@@ -0,0 +1,4 @@
+package model
+
+// Regions are the regions of a Record by name.
+type Regions map[string]*Region
@@ -1,5 +1,9 @@
 package model
 
+import (
+	"time"
+)
+
 type Usage struct {
 	Zone    string      `json:"zone"`
 	Domain  string      `json:"domain"`
@@ -9,3 +13,30 @@ type Usage struct {
 	Graph   [][]float64 `json:"graph"`
 	Records float64     `json:"records"`
 }
+
+// CompleteBuckets returns all complete buckets of Graph. The last data point of Graph
+// is the current (incomplete) bucket and ignored.
+func (instance Usage) CompleteBuckets() []UsageBucket {
+	result := []UsageBucket{}
+	for i := 0; i < len(instance.Graph)-1; i++ {
+		point := instance.Graph[i]
+		if len(point) < 2 {
+			continue
+		}
+		result = append(result, UsageBucket{
+			Start:   time.Unix(int64(point[0]), 0),
+			Queries: point[1],
+		})
+	}
+	return result
+}
+
+// LastCompleteBucket returns the last complete bucket of Graph. If there is no complete
+// bucket ok is false.
+func (instance Usage) LastCompleteBucket() (bucket UsageBucket, ok bool) {
+	buckets := instance.CompleteBuckets()
+	if len(buckets) <= 0 {
+		return UsageBucket{}, false
+	}
+	return buckets[len(buckets)-1], true
+}
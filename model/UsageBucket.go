@@ -0,0 +1,11 @@
+package model
+
+import (
+	"time"
+)
+
+// UsageBucket is one data point of the graph of a Usage.
+type UsageBucket struct {
+	Start   time.Time
+	Queries float64
+}
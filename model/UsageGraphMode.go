@@ -0,0 +1,92 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// UsageGraphMode describes how the graph of a Usage is exported.
+type UsageGraphMode string
+
+const (
+	// UG_OFF does not export the graph at all.
+	UG_OFF UsageGraphMode = "off"
+	// UG_LAST_BUCKET exports the value of the last complete bucket without timestamp.
+	UG_LAST_BUCKET UsageGraphMode = "last-bucket"
+	// UG_TIMESTAMPS exports the value of the last complete bucket with the timestamp of the bucket.
+	UG_TIMESTAMPS UsageGraphMode = "timestamps"
+)
+
+// AllUsageGraphModes contains all possible variants of UsageGraphMode.
+var AllUsageGraphModes = []UsageGraphMode{
+	UG_OFF,
+	UG_LAST_BUCKET,
+	UG_TIMESTAMPS,
+}
+
+// IsEnabled returns true if the graph should be exported at all.
+func (instance UsageGraphMode) IsEnabled() bool {
+	return instance == UG_LAST_BUCKET || instance == UG_TIMESTAMPS
+}
+
+func (instance UsageGraphMode) String() string {
+	s, err := instance.CheckedString()
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// CheckedString is like String but return also an optional error if there are some
+// validation errors.
+func (instance UsageGraphMode) CheckedString() (string, error) {
+	if instance == "" {
+		return string(UG_OFF), nil
+	}
+	for _, candidate := range AllUsageGraphModes {
+		if candidate == instance {
+			return string(instance), nil
+		}
+	}
+	return "", fmt.Errorf("Illegal usage graph mode: %s", string(instance))
+}
+
+// Set sets the value and checks for potential errors.
+func (instance *UsageGraphMode) Set(value string) error {
+	lowerValue := strings.ToLower(strings.TrimSpace(value))
+	for _, candidate := range AllUsageGraphModes {
+		if candidate.String() == lowerValue {
+			(*instance) = candidate
+			return nil
+		}
+	}
+	return fmt.Errorf("Illegal usage graph mode: %s", value)
+}
+
+// MarshalJSON is used until json marshalling. Do not call directly.
+func (instance UsageGraphMode) MarshalJSON() ([]byte, error) {
+	s, err := instance.CheckedString()
+	if err != nil {
+		return []byte{}, err
+	}
+	return json.Marshal(s)
+}
+
+// UnmarshalJSON is used until json unmarshalling. Do not call directly.
+func (instance *UsageGraphMode) UnmarshalJSON(b []byte) error {
+	var value string
+	if err := json.Unmarshal(b, &value); err != nil {
+		return err
+	}
+	return instance.Set(value)
+}
+
+// UnmarshalYAML is used until yaml unmarshalling. Do not call directly.
+func (instance *UsageGraphMode) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value string
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+	return instance.Set(value)
+}
@@ -15,4 +15,10 @@ type Zone struct {
 	DnsServers   []string  `json:"dns_servers"`
 	Records      []*Record `json:"records"`
 	Link         string    `json:"link"`
+	// PrimaryMaster is the name server of NSONE that is the primary master of this zone.
+	PrimaryMaster string `json:"primary_master"`
+	// Primary is provided if NSONE could be the primary of this zone for other name servers.
+	Primary *ZonePrimary `json:"primary"`
+	// Secondary is provided if NSONE could be a secondary of this zone.
+	Secondary *ZoneSecondary `json:"secondary"`
 }
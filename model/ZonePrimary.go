@@ -0,0 +1,7 @@
+package model
+
+// ZonePrimary describes whether NSONE is the primary of a zone for other name servers.
+type ZonePrimary struct {
+	Enabled     bool                   `json:"enabled"`
+	Secondaries []*ZoneSecondaryServer `json:"secondaries"`
+}
@@ -0,0 +1,26 @@
+package model
+
+// ZoneSecondary describes whether NSONE is a secondary of a zone which is transferred from
+// other masters and the state of the last transfer.
+type ZoneSecondary struct {
+	Enabled     bool     `json:"enabled"`
+	PrimaryIp   string   `json:"primary_ip"`
+	PrimaryPort int      `json:"primary_port"`
+	OtherIps    []string `json:"other_ips"`
+	OtherPorts  []int    `json:"other_ports"`
+	// LastXfr is the unix timestamp of the last transfer (0 if there was none yet).
+	LastXfr int64  `json:"last_xfr"`
+	Status  string `json:"status"`
+	// Error is the reason why the last transfer failed (empty if it did not).
+	Error   string `json:"error"`
+	Expired bool   `json:"expired"`
+}
+
+// Masters returns the addresses of all masters this zone is transferred from.
+func (instance ZoneSecondary) Masters() []string {
+	result := []string{}
+	if len(instance.PrimaryIp) > 0 {
+		result = append(result, instance.PrimaryIp)
+	}
+	return append(result, instance.OtherIps...)
+}
@@ -0,0 +1,9 @@
+package model
+
+// ZoneSecondaryServer is a name server that is allowed to transfer a zone of which NSONE
+// is the primary.
+type ZoneSecondaryServer struct {
+	Ip     string `json:"ip"`
+	Port   int    `json:"port"`
+	Notify bool   `json:"notify"`
+}
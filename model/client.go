@@ -1,100 +1,143 @@
 package model
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/echocat/nsone_exporter/utils"
-	"net"
 	"net/http"
 	"net/url"
-	"sync"
+	"strings"
 	"time"
 	"github.com/prometheus/common/log"
-	"crypto/tls"
 )
 
-const apiRootUri = "https://api.nsone.net/v1"
+// DefaultApiUri is the base URI of the public NSONE v1 API.
+const DefaultApiUri = "https://api.nsone.net/v1"
 
 type Client struct {
 	uri                                  string
 	accessToken                          string
-	maximumNumberOfConcurrentConnections int
-	numberOfActiveConnections            int
-	condition                            *sync.Cond
+	connections                          chan struct{}
 	client                               *http.Client
+	rateLimiter                          *RateLimiter
+	observer                             ClientObserver
+	options                              ClientOptions
 }
 
-func NewClient(accessToken string, timeout time.Duration, maximumNumberOfConcurrentConnections int) *Client {
+// endpointTemplates contains the placeholders of the path elements of every resource
+// (by first path element) after the given number of fixed path elements.
+var endpointTemplates = map[string]struct {
+	fixed        int
+	placeholders []string
+}{
+	"zones": {1, []string{"{zone}", "{record}", "{type}"}},
+	"stats": {2, []string{"{zone}", "{record}", "{type}"}},
+	// Literal placeholders keep the fixed elements between identifiers, empty ones keep
+	// whatever element is there (like 'data' or 'availability').
+	"pulsar":     {2, []string{"{app}", "jobs", "{job}", ""}},
+	"monitoring": {2, []string{"{job}"}},
+	"data":       {2, []string{"{source}"}},
+}
+
+func NewClient(options ClientOptions) (*Client, error) {
+	uri := options.ApiUri
+	if len(uri) <= 0 {
+		uri = DefaultApiUri
+	}
+	maximumNumberOfConcurrentConnections := options.MaximumNumberOfConcurrentConnections
+	if maximumNumberOfConcurrentConnections <= 0 {
+		maximumNumberOfConcurrentConnections = 1
+	}
+	transport, err := options.transport()
+	if err != nil {
+		return nil, err
+	}
+	observer := options.Observer
+	if observer == nil {
+		observer = noopClientObserver{}
+	}
 	return &Client{
-		accessToken: accessToken,
-		maximumNumberOfConcurrentConnections: maximumNumberOfConcurrentConnections,
-		numberOfActiveConnections: 0,
-		condition:   &sync.Cond{L: &sync.Mutex{}},
+		uri:         strings.TrimSuffix(uri, "/"),
+		accessToken: options.AccessToken,
+		connections: make(chan struct{}, maximumNumberOfConcurrentConnections),
 		client: &http.Client{
-			Transport: &http.Transport{
-				MaxIdleConnsPerHost: 100,
-				TLSClientConfig: &tls.Config{
-					RootCAs: utils.LoadInternalCaBunlde(),
-				},
-				Dial: func(netw, addr string) (net.Conn, error) {
-					c, err := net.DialTimeout(netw, addr, timeout)
-					if err != nil {
-						return nil, err
-					}
-					if err := c.SetDeadline(time.Now().Add(timeout)); err != nil {
-						return nil, err
-					}
-					return c, nil
-				},
-			},
+			Transport: transport,
 		},
+		rateLimiter: NewRateLimiter(),
+		observer:    observer,
+		options:     options,
+	}, nil
+}
+
+// Options returns the options this client was created with.
+func (instance *Client) Options() ClientOptions {
+	return instance.options
+}
+
+// CloseIdleConnections closes every connection which is currently not in use. Should be
+// called if this client is not used anymore.
+func (instance *Client) CloseIdleConnections() {
+	if transport, ok := instance.client.Transport.(interface{ CloseIdleConnections() }); ok {
+		transport.CloseIdleConnections()
 	}
 }
 
-func (instance *Client) GetZones(expandZones bool) (*Zones, error) {
+// NumberOfActiveConnections returns the number of requests that are currently in flight.
+func (instance *Client) NumberOfActiveConnections() int {
+	return len(instance.connections)
+}
+
+// RateLimiterStats returns the current state of the rate limiter that paces all
+// requests of this client.
+func (instance *Client) RateLimiterStats() RateLimiterStats {
+	return instance.rateLimiter.Stats()
+}
+
+// GetZones returns all zones of the account. If expandZones is true also the records
+// of every zone are retrieved. If this fails for some zones, the successfully expanded
+// zones are returned together with an *ExpandZonesError.
+func (instance *Client) GetZones(ctx context.Context, expandZones bool) (*Zones, error) {
 	uri, err := instance.zonesUriFor("", "", RT_NONE)
 	if err != nil {
 		return nil, err
 	}
 	result := &Zones{}
-	err = instance.executeAndEvaluateUri(uri, err, result)
+	err = instance.executeAndEvaluateUri(ctx, uri, err, result)
 	if err != nil {
 		return nil, err
 	}
 	if expandZones {
-		instance.expandZonesOf(result)
-		if err != nil {
-			return nil, err
-		}
+		return instance.expandZonesOf(ctx, result)
 	}
 	return result, nil
 }
 
-func (instance *Client) GetZone(zone string) (*Zone, error) {
+func (instance *Client) GetZone(ctx context.Context, zone string) (*Zone, error) {
 	uri, err := instance.zonesUriFor(zone, "", RT_NONE)
 	result := &Zone{}
-	err = instance.executeAndEvaluateUri(uri, err, result)
+	err = instance.executeAndEvaluateUri(ctx, uri, err, result)
 	if err != nil {
 		return nil, err
 	}
 	return result, nil
 }
 
-func (instance *Client) GetRecord(zone string, record string, recordType RecordType) (*Record, error) {
+func (instance *Client) GetRecord(ctx context.Context, zone string, record string, recordType RecordType) (*Record, error) {
 	uri, err := instance.zonesUriFor(zone, record, recordType)
 	result := &Record{}
-	err = instance.executeAndEvaluateUri(uri, err, result)
+	err = instance.executeAndEvaluateUri(ctx, uri, err, result)
 	if err != nil {
 		return nil, err
 	}
 	return result, nil
 }
 
-func (instance *Client) GetAccountUsage(period StatsPeriod) (*Usage, error) {
+func (instance *Client) GetAccountUsage(ctx context.Context, period StatsPeriod) (*Usage, error) {
 	uri, err := instance.usagesUriFor("", "", RT_NONE, false, period)
 	result := &Usages{}
-	err = instance.executeAndEvaluateUri(uri, err, result)
+	err = instance.executeAndEvaluateUri(ctx, uri, err, result)
 	if err != nil {
 		return nil, err
 	}
@@ -104,88 +147,212 @@ func (instance *Client) GetAccountUsage(period StatsPeriod) (*Usage, error) {
 	return (*result)[0], nil
 }
 
-func (instance *Client) GetZonesUsage(period StatsPeriod) (*Usages, error) {
+func (instance *Client) GetZonesUsage(ctx context.Context, period StatsPeriod) (*Usages, error) {
 	uri, err := instance.usagesUriFor("", "", RT_NONE, true, period)
 	result := &Usages{}
-	err = instance.executeAndEvaluateUri(uri, err, result)
+	err = instance.executeAndEvaluateUri(ctx, uri, err, result)
 	if err != nil {
 		return nil, err
 	}
 	return result, nil
 }
 
-func (instance *Client) GetZoneUsage(zone string, period StatsPeriod) (*Usages, error) {
+func (instance *Client) GetZoneUsage(ctx context.Context, zone string, period StatsPeriod) (*Usages, error) {
 	uri, err := instance.usagesUriFor(zone, "", RT_NONE, false, period)
 	result := &Usages{}
-	err = instance.executeAndEvaluateUri(uri, err, result)
+	err = instance.executeAndEvaluateUri(ctx, uri, err, result)
 	if err != nil {
 		return nil, err
 	}
 	return result, nil
 }
 
-func (instance *Client) GetRecordsUsage(zone string, period StatsPeriod) (*Usages, error) {
+func (instance *Client) GetRecordsUsage(ctx context.Context, zone string, period StatsPeriod) (*Usages, error) {
 	uri, err := instance.usagesUriFor(zone, "", RT_NONE, true, period)
 	result := &Usages{}
-	err = instance.executeAndEvaluateUri(uri, err, result)
+	err = instance.executeAndEvaluateUri(ctx, uri, err, result)
 	if err != nil {
 		return nil, err
 	}
 	return result, nil
 }
 
-func (instance *Client) GetRecordUsage(zone string, record string, recordType RecordType, period StatsPeriod) (*Usages, error) {
+func (instance *Client) GetRecordUsage(ctx context.Context, zone string, record string, recordType RecordType, period StatsPeriod) (*Usages, error) {
 	uri, err := instance.usagesUriFor(zone, record, recordType, false, period)
 	result := &Usages{}
-	err = instance.executeAndEvaluateUri(uri, err, result)
+	err = instance.executeAndEvaluateUri(ctx, uri, err, result)
 	if err != nil {
 		return nil, err
 	}
 	return result, nil
 }
 
-func (instance *Client) GetAccountQps() (float64, error) {
+func (instance *Client) GetAccountQps(ctx context.Context) (float64, error) {
 	uri, err := instance.qpsUriFor("", "", RT_NONE)
 	result := &QpsStat{}
-	err = instance.executeAndEvaluateUri(uri, err, result)
+	err = instance.executeAndEvaluateUri(ctx, uri, err, result)
 	if err != nil {
 		return 0, err
 	}
 	return result.Qps, nil
 }
 
-func (instance *Client) GetZoneQps(zone string) (float64, error) {
+func (instance *Client) GetZoneQps(ctx context.Context, zone string) (float64, error) {
 	uri, err := instance.qpsUriFor(zone, "", RT_NONE)
 	result := &QpsStat{}
-	err = instance.executeAndEvaluateUri(uri, err, result)
+	err = instance.executeAndEvaluateUri(ctx, uri, err, result)
 	if err != nil {
 		return 0, err
 	}
 	return result.Qps, nil
 }
 
-func (instance *Client) GetRecordQps(zone string, record string, recordType RecordType) (float64, error) {
+func (instance *Client) GetRecordQps(ctx context.Context, zone string, record string, recordType RecordType) (float64, error) {
 	uri, err := instance.qpsUriFor(zone, record, recordType)
 	result := &QpsStat{}
-	err = instance.executeAndEvaluateUri(uri, err, result)
+	err = instance.executeAndEvaluateUri(ctx, uri, err, result)
 	if err != nil {
 		return 0, err
 	}
 	return result.Qps, nil
 }
 
-func (instance *Client) expandZonesOf(zones *Zones) error {
+// GetPulsarApps returns all applications of NSONE Pulsar of the account.
+func (instance *Client) GetPulsarApps(ctx context.Context) (*PulsarApps, error) {
+	uri, err := instance.pulsarUriFor("")
+	result := &PulsarApps{}
+	err = instance.executeAndEvaluateUri(ctx, uri, err, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetPulsarJobs returns all jobs of the given Pulsar application.
+func (instance *Client) GetPulsarJobs(ctx context.Context, app string) (*PulsarJobs, error) {
+	uri, err := instance.pulsarUriFor(app)
+	result := &PulsarJobs{}
+	err = instance.executeAndEvaluateUri(ctx, uri, err, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetPulsarJobPerformance returns the median latency of the given job of the given Pulsar
+// application by geo region for the given period.
+func (instance *Client) GetPulsarJobPerformance(ctx context.Context, app string, job string, period StatsPeriod) (*PulsarJobGraph, error) {
+	uri, err := instance.pulsarJobUriFor(app, job, "data", period, "&agg=p50")
+	result := &PulsarJobGraph{}
+	err = instance.executeAndEvaluateUri(ctx, uri, err, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetPulsarJobAvailability returns the availability of the given job of the given Pulsar
+// application by geo region for the given period.
+func (instance *Client) GetPulsarJobAvailability(ctx context.Context, app string, job string, period StatsPeriod) (*PulsarJobGraph, error) {
+	uri, err := instance.pulsarJobUriFor(app, job, "availability", period, "")
+	result := &PulsarJobGraph{}
+	err = instance.executeAndEvaluateUri(ctx, uri, err, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetMonitoringJobs returns all monitoring jobs of the account including their status.
+func (instance *Client) GetMonitoringJobs(ctx context.Context) (*MonitoringJobs, error) {
+	uri, err := instance.monitoringJobsUriFor("")
+	result := &MonitoringJobs{}
+	err = instance.executeAndEvaluateUri(ctx, uri, err, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetMonitoringJobStatus returns the current status of the given monitoring job by region.
+func (instance *Client) GetMonitoringJobStatus(ctx context.Context, job string) (MonitoringJobStatuses, error) {
+	if job == "" {
+		return nil, errors.New("It is not possible to get the status without job.")
+	}
+	uri, err := instance.monitoringJobsUriFor(job)
+	result := &MonitoringJob{}
+	err = instance.executeAndEvaluateUri(ctx, uri, err, result)
+	if err != nil {
+		return nil, err
+	}
+	return result.Status, nil
+}
+
+// GetMonitoringJobMetrics returns the metrics (like rtt) of the given monitoring job by
+// region for the given period.
+func (instance *Client) GetMonitoringJobMetrics(ctx context.Context, job string, period StatsPeriod) (*MonitoringJobMetrics, error) {
+	uri, err := instance.monitoringMetricsUriFor(job, period)
+	result := &MonitoringJobMetrics{}
+	err = instance.executeAndEvaluateUri(ctx, uri, err, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetDataSources returns all data sources of the account.
+func (instance *Client) GetDataSources(ctx context.Context) (*DataSources, error) {
+	uri, err := instance.dataUriFor("sources", "")
+	result := &DataSources{}
+	err = instance.executeAndEvaluateUri(ctx, uri, err, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetDataFeeds returns all feeds of the given data source.
+func (instance *Client) GetDataFeeds(ctx context.Context, source string) (*DataFeeds, error) {
+	if source == "" {
+		return nil, errors.New("It is not possible to get feeds without source.")
+	}
+	uri, err := instance.dataUriFor("feeds", source)
+	result := &DataFeeds{}
+	err = instance.executeAndEvaluateUri(ctx, uri, err, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (instance *Client) expandZonesOf(ctx context.Context, zones *Zones) (*Zones, error) {
 	futures := utils.WorkerFutures{}
 	for _, zone := range *zones {
-		instance.submitExpandZone(zone, &futures)
+		instance.submitExpandZone(ctx, zone, &futures)
+	}
+	futures.WaitAll(ctx)
+	result := Zones{}
+	var expandErr *ExpandZonesError
+	for i, future := range futures {
+		zone := (*zones)[i]
+		if err := future.Wait(ctx); err != nil {
+			if expandErr == nil {
+				expandErr = &ExpandZonesError{Errors: map[string]error{}}
+			}
+			expandErr.Errors[zone.Name] = err
+		} else {
+			result = append(result, zone)
+		}
+	}
+	if expandErr != nil {
+		return &result, expandErr
 	}
-	err := futures.Wait()
-	return err
+	return &result, nil
 }
 
-func (instance *Client) submitExpandZone(zone *Zone, registerAt *utils.WorkerFutures) {
-	future := utils.NewWorkerFutureFor(func() error {
-		fullZone, err := instance.GetZone(zone.Name)
+func (instance *Client) submitExpandZone(ctx context.Context, zone *Zone, registerAt *utils.WorkerFutures) {
+	future := utils.NewWorkerFutureFor(ctx, func() error {
+		fullZone, err := instance.GetZone(ctx, zone.Name)
 		if err != nil {
 			return fmt.Errorf("Could not retreive detailed infomation about zone %v. Cause: %v", zone.Name, err)
 		}
@@ -196,8 +363,8 @@ func (instance *Client) submitExpandZone(zone *Zone, registerAt *utils.WorkerFut
 	registerAt.Append(future)
 }
 
-func (instance *Client) requestFor(url *url.URL) *http.Request {
-	return &http.Request{
+func (instance *Client) requestFor(ctx context.Context, url *url.URL) *http.Request {
+	request := &http.Request{
 		Method:     "GET",
 		URL:        url,
 		Proto:      "HTTP/1.1",
@@ -208,19 +375,23 @@ func (instance *Client) requestFor(url *url.URL) *http.Request {
 		},
 		Host: url.Host,
 	}
+	return request.WithContext(ctx)
 }
 
-func (instance *Client) executeAndEvaluateUri(uri *url.URL, err error, target interface{}) error {
+func (instance *Client) executeAndEvaluateUri(ctx context.Context, uri *url.URL, err error, target interface{}) error {
 	if err != nil {
 		return err
 	}
-	request := instance.requestFor(uri)
+	request := instance.requestFor(ctx, uri)
 	return instance.executeAndEvaluateRequest(request, target)
 }
 
 func (instance *Client) executeAndEvaluateRequest(request *http.Request, target interface{}) error {
 	var response *http.Response
-	instance.increaseUsageCount()
+	ctx := request.Context()
+	if err := instance.increaseUsageCount(ctx); err != nil {
+		return fmt.Errorf("Could not execute request %v. Got: %v", request.URL, err)
+	}
 	defer func() {
 		instance.decreaseUsageCount()
 		if response != nil && response.Body != nil {
@@ -228,19 +399,48 @@ func (instance *Client) executeAndEvaluateRequest(request *http.Request, target
 		}
 	}()
 	var err error
+	endpoint := instance.endpointOf(request.URL)
+	retry := true
 	waitBeforeRetry := 0
-	for i := 0; (i == 0 || waitBeforeRetry > 0) && i < 20; i++ {
+	for i := 0; retry && i < 20; i++ {
+		if i > 0 {
+			instance.observer.ObserveRetry(endpoint)
+		}
+		retry = false
+		if response != nil && response.Body != nil {
+			response.Body.Close()
+		}
 		if waitBeforeRetry > 0 {
-			time.Sleep(time.Duration(i * waitBeforeRetry) * time.Millisecond)
+			err = sleep(ctx, time.Duration(i * waitBeforeRetry) * time.Millisecond)
 			waitBeforeRetry = 0
+			if err != nil {
+				response = nil
+				break
+			}
+		}
+		if err = instance.rateLimiter.Wait(ctx); err != nil {
+			response = nil
+			break
 		}
+		start := time.Now()
 		response, err = instance.client.Do(request)
-		if hasTimeoutError(err) {
+		if err == nil {
+			instance.observer.ObserveRequest(endpoint, response.StatusCode, time.Since(start))
+			instance.rateLimiter.Update(response)
+		} else {
+			instance.observer.ObserveRequest(endpoint, 0, time.Since(start))
+		}
+		if hasTimeoutError(err) && ctx.Err() == nil {
+			retry = true
 			waitBeforeRetry = 50
 			log.Warnf("Got timeout error while execute %v. Slow down and retry...", request.URL)
 		}
 		if err == nil && response.StatusCode == 429 {
-			waitBeforeRetry = 2000
+			retry = true
+			if !instance.rateLimiter.Knows() {
+				// Without the limits of NSONE we could only guess how long to wait.
+				waitBeforeRetry = 2000
+			}
 			log.Warnf("Got 'Rate limit exceeded' error while execute %v. Slow down and retry...", request.URL)
 		}
 	}
@@ -270,6 +470,42 @@ func (instance notFoundError) Error() string {
 	return fmt.Sprintf("Could not execute request: %v. Got: 404 not found.", instance.URL)
 }
 
+// ExpandZonesError is returned by GetZones if some zones could not be expanded.
+type ExpandZonesError struct {
+	// Errors contains the cause by name of every zone that could not be expanded.
+	Errors map[string]error
+}
+
+func (instance ExpandZonesError) Error() string {
+	return fmt.Sprintf("Could not expand %d zones.", len(instance.Errors))
+}
+
+// endpointOf returns the path of the given uri relative to the base URI of the API with
+// all identifiers replaced by placeholders. Example: '/zones/{zone}'
+func (instance *Client) endpointOf(uri *url.URL) string {
+	path := uri.Path
+	if base, err := url.Parse(instance.uri); err == nil {
+		path = strings.TrimPrefix(path, strings.TrimSuffix(base.Path, "/"))
+	}
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	template, ok := endpointTemplates[parts[0]]
+	for i := range parts {
+		if !ok && i > 0 {
+			parts[i] = "{id}"
+		} else if ok && i >= template.fixed {
+			placeholder := i - template.fixed
+			if placeholder < len(template.placeholders) {
+				if template.placeholders[placeholder] != "" {
+					parts[i] = template.placeholders[placeholder]
+				}
+			} else {
+				parts[i] = "{id}"
+			}
+		}
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
 func hasTimeoutError(err error) bool {
 	if err == nil {
 		return false
@@ -280,25 +516,33 @@ func hasTimeoutError(err error) bool {
 	return false
 }
 
-func (instance *Client) increaseUsageCount() {
-	instance.condition.L.Lock()
-	defer instance.condition.L.Unlock()
-	for instance.numberOfActiveConnections >= instance.maximumNumberOfConcurrentConnections {
-		instance.condition.Wait()
+func (instance *Client) increaseUsageCount(ctx context.Context) error {
+	select {
+	case instance.connections <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-	instance.numberOfActiveConnections++
 }
 
-
 func (instance *Client) decreaseUsageCount() {
-	instance.condition.L.Lock()
-	defer instance.condition.L.Unlock()
-	instance.numberOfActiveConnections--
-	instance.condition.Broadcast()
+	<-instance.connections
+}
+
+// sleep waits for the given duration or until ctx is done.
+func sleep(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func (instance *Client) zonesUriFor(zone string, record string, recordType RecordType) (*url.URL, error) {
-	uri := fmt.Sprintf("%s/zones", apiRootUri)
+	uri := fmt.Sprintf("%s/zones", instance.uri)
 	if zone != "" {
 		uri += fmt.Sprintf("/%s", zone)
 		if record != "" {
@@ -318,7 +562,7 @@ func (instance *Client) zonesUriFor(zone string, record string, recordType Recor
 }
 
 func (instance *Client) usagesUriFor(zone string, record string, recordType RecordType, expand bool, period StatsPeriod) (*url.URL, error) {
-	uri := fmt.Sprintf("%s/stats/usage", apiRootUri)
+	uri := fmt.Sprintf("%s/stats/usage", instance.uri)
 	if zone != "" {
 		uri += fmt.Sprintf("/%s", zone)
 		if record != "" {
@@ -339,7 +583,7 @@ func (instance *Client) usagesUriFor(zone string, record string, recordType Reco
 }
 
 func (instance *Client) qpsUriFor(zone string, record string, recordType RecordType) (*url.URL, error) {
-	uri := fmt.Sprintf("%s/stats/qps", apiRootUri)
+	uri := fmt.Sprintf("%s/stats/qps", instance.uri)
 	if zone != "" {
 		uri += fmt.Sprintf("/%s", zone)
 		if record != "" {
@@ -357,3 +601,63 @@ func (instance *Client) qpsUriFor(zone string, record string, recordType RecordT
 	}
 	return result, nil
 }
+
+func (instance *Client) pulsarUriFor(app string) (*url.URL, error) {
+	uri := fmt.Sprintf("%s/pulsar/apps", instance.uri)
+	if app != "" {
+		uri += fmt.Sprintf("/%s/jobs", app)
+	}
+	result, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("Could not create pulsar uri for app=%s. Cause: %v", app, err)
+	}
+	return result, nil
+}
+
+func (instance *Client) pulsarJobUriFor(app string, job string, resource string, period StatsPeriod, parameters string) (*url.URL, error) {
+	if app == "" || job == "" {
+		return nil, fmt.Errorf("It is not possible to get %s of a Pulsar job without app and job.", resource)
+	}
+	uri := fmt.Sprintf("%s/pulsar/apps/%s/jobs/%s/%s?period=%v&geo=*%s", instance.uri, app, job, resource, period, parameters)
+	result, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("Could not create pulsar %s uri for app=%s and job=%s. Cause: %v", resource, app, job, err)
+	}
+	return result, nil
+}
+
+func (instance *Client) monitoringJobsUriFor(job string) (*url.URL, error) {
+	uri := fmt.Sprintf("%s/monitoring/jobs", instance.uri)
+	if job != "" {
+		uri += fmt.Sprintf("/%s", job)
+	}
+	result, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("Could not create monitoring jobs uri for job=%s. Cause: %v", job, err)
+	}
+	return result, nil
+}
+
+func (instance *Client) monitoringMetricsUriFor(job string, period StatsPeriod) (*url.URL, error) {
+	if job == "" {
+		return nil, errors.New("It is not possible to get metrics without job.")
+	}
+	uri := fmt.Sprintf("%s/monitoring/metrics/%s?period=%v", instance.uri, job, period)
+	result, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("Could not create monitoring metrics uri for job=%s. Cause: %v", job, err)
+	}
+	return result, nil
+}
+
+func (instance *Client) dataUriFor(resource string, source string) (*url.URL, error) {
+	uri := fmt.Sprintf("%s/data/%s", instance.uri, resource)
+	if source != "" {
+		uri += fmt.Sprintf("/%s", source)
+	}
+	result, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("Could not create data uri for %s of source=%s. Cause: %v", resource, source, err)
+	}
+	return result, nil
+}
@@ -0,0 +1,246 @@
+package model_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/echocat/nsone_exporter/model"
+	"github.com/echocat/nsone_exporter/nsonetest"
+)
+
+const testAccessToken = "test-token"
+
+func newTestServer() *nsonetest.Server {
+	server := nsonetest.NewServer(testAccessToken)
+	server.AddZone(&model.Zone{Name: "example.com", TTL: 3600, Records: []*model.Record{
+		{Name: "www.example.com", Type: model.RT_A, ShortAnswers: []string{"1.2.3.4"}, TTL: 60},
+	}})
+	return server
+}
+
+func newTestClient(t *testing.T, options model.ClientOptions) *model.Client {
+	client, err := model.NewClient(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestClientRetriesRateLimitedRequests(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	server.SetRateLimit(100, time.Second)
+	server.RateLimited("/zones", 2)
+	client := newTestClient(t, server.ClientOptions())
+
+	zones, err := client.GetZones(context.Background(), false)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*zones) != 1 {
+		t.Errorf("Expected 1 zone but got %d.", len(*zones))
+	}
+	if requests := server.Requests("/zones"); requests != 3 {
+		t.Errorf("Expected 3 requests but got %d.", requests)
+	}
+	if rateLimited := client.RateLimiterStats().RateLimited; rateLimited != 2 {
+		t.Errorf("Expected 2 rate limited requests but got %d.", rateLimited)
+	}
+}
+
+func TestClientStopsRetryingRateLimitedRequestsIfContextIsDone(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	server.RateLimited("/zones", 0)
+	client := newTestClient(t, server.ClientOptions())
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+
+	_, err := client.GetZones(ctx, false)
+
+	if err == nil {
+		t.Fatal("Expected an error but got none.")
+	}
+	if duration := time.Since(start); duration > time.Second {
+		t.Errorf("Expected to give up after the context is done but it took %v.", duration)
+	}
+}
+
+func TestClientLearnsRateLimitFromHeaders(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	server.SetRateLimit(10, 10*time.Second)
+	client := newTestClient(t, server.ClientOptions())
+
+	if _, err := client.GetZones(context.Background(), false); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := client.RateLimiterStats()
+	if !stats.Known || stats.Limit != 10 || stats.Period != 10*time.Second {
+		t.Errorf("Expected a known limit of 10 per 10s but got %+v.", stats)
+	}
+	if stats.Remaining > 9.5 {
+		t.Errorf("Expected at most 9 remaining requests but got %v.", stats.Remaining)
+	}
+}
+
+func TestClientThrottlesRequestsBeforeRateLimitIsExceeded(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	server.SetRateLimit(5, time.Second)
+	client := newTestClient(t, server.ClientOptions())
+
+	for i := 0; i < 8; i++ {
+		if _, err := client.GetZone(context.Background(), "example.com"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if throttled := client.RateLimiterStats().Throttled; throttled <= 0 {
+		t.Errorf("Expected throttled requests but there are none.")
+	}
+}
+
+func TestClientRetriesRequestsThatTimedOut(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	server.Inject("/zones/example.com", nsonetest.Fault{Delay: 500 * time.Millisecond, Times: 1})
+	options := server.ClientOptions()
+	options.Timeout = 100 * time.Millisecond
+	client := newTestClient(t, options)
+
+	zone, err := client.GetZone(context.Background(), "example.com")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if zone.Name != "example.com" {
+		t.Errorf("Expected zone example.com but got %s.", zone.Name)
+	}
+	if requests := server.Requests("/zones/example.com"); requests != 2 {
+		t.Errorf("Expected 2 requests but got %d.", requests)
+	}
+}
+
+func TestClientDoesNotRetryOtherErrors(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	server.Inject("/zones/example.com", nsonetest.Fault{StatusCode: http.StatusInternalServerError})
+	server.Malformed("/zones")
+	client := newTestClient(t, server.ClientOptions())
+
+	if _, err := client.GetZone(context.Background(), "example.com"); err == nil {
+		t.Error("Expected an error for status 500 but got none.")
+	}
+	if _, err := client.GetZone(context.Background(), "unknown.org"); err == nil {
+		t.Error("Expected an error for an unknown zone but got none.")
+	}
+	if _, err := client.GetZones(context.Background(), false); err == nil {
+		t.Error("Expected an error for a malformed response but got none.")
+	}
+	for _, path := range []string{"/zones/example.com", "/zones/unknown.org", "/zones"} {
+		if requests := server.Requests(path); requests != 1 {
+			t.Errorf("Expected 1 request of %s but got %d.", path, requests)
+		}
+	}
+}
+
+func TestClientIgnoresTrailingSlashOfApiUri(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	options := server.ClientOptions()
+	options.ApiUri += "/"
+	client := newTestClient(t, options)
+
+	if _, err := client.GetZones(context.Background(), false); err != nil {
+		t.Fatal(err)
+	}
+	if requests := server.Requests("/zones"); requests != 1 {
+		t.Errorf("Expected 1 request of /zones but got %d.", requests)
+	}
+}
+
+func TestClientGetsStatusOfMonitoringJob(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	server.AddMonitoringJob(&model.MonitoringJob{Id: "m1", Name: "web-check", Status: model.MonitoringJobStatuses{
+		"global": {Since: 100, Status: "up"},
+		"lga":    {Since: 200, Status: "down"},
+	}})
+	client := newTestClient(t, server.ClientOptions())
+
+	statuses, err := client.GetMonitoringJobStatus(context.Background(), "m1")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 2 || !statuses["global"].IsUp() || statuses["lga"].IsUp() || statuses["lga"].Since != 200 {
+		t.Errorf("Expected the status of every region but got %+v.", statuses)
+	}
+	if _, err := client.GetMonitoringJobStatus(context.Background(), "unknown"); err == nil {
+		t.Error("Expected an error for an unknown job but got none.")
+	}
+	if _, err := client.GetMonitoringJobStatus(context.Background(), ""); err == nil {
+		t.Error("Expected an error for an empty job but got none.")
+	}
+}
+
+// recordingObserver records every observed request as '<endpoint> <code>' and every retry.
+type recordingObserver struct {
+	lock     sync.Mutex
+	requests []string
+	retries  []string
+}
+
+func (instance *recordingObserver) ObserveRequest(endpoint string, statusCode int, duration time.Duration) {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	instance.requests = append(instance.requests, fmt.Sprintf("%s %d", endpoint, statusCode))
+}
+
+func (instance *recordingObserver) ObserveRetry(endpoint string) {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	instance.retries = append(instance.retries, endpoint)
+}
+
+func TestClientObservesRequestsByTemplatedEndpoint(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	server.SetRateLimit(100, time.Second)
+	server.RateLimited("/zones", 1)
+	server.Inject("/zones/example.com/www.example.com/A", nsonetest.Fault{StatusCode: http.StatusInternalServerError})
+	server.AddPulsarApp(&model.PulsarApp{Id: "app1", Name: "web"})
+	server.AddPulsarJob(&model.PulsarJob{Id: "job1", AppId: "app1", Name: "cdn"})
+	observer := &recordingObserver{}
+	options := server.ClientOptions()
+	options.Observer = observer
+	client := newTestClient(t, options)
+
+	client.GetZones(context.Background(), false)
+	client.GetRecord(context.Background(), "example.com", "www.example.com", model.RT_A)
+	client.GetPulsarJobPerformance(context.Background(), "app1", "job1", model.P_HOURLY)
+	client.GetPulsarJobAvailability(context.Background(), "app1", "job1", model.P_HOURLY)
+
+	expected := []string{
+		"/zones 429",
+		"/zones 200",
+		"/zones/{zone}/{record}/{type} 500",
+		"/pulsar/apps/{app}/jobs/{job}/data 200",
+		"/pulsar/apps/{app}/jobs/{job}/availability 200",
+	}
+	if !reflect.DeepEqual(observer.requests, expected) {
+		t.Errorf("Expected requests %v but got %v.", expected, observer.requests)
+	}
+	if !reflect.DeepEqual(observer.retries, []string{"/zones"}) {
+		t.Errorf("Expected one retry of /zones but got %v.", observer.retries)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"github.com/echocat/nsone_exporter/model"
+	"github.com/prometheus/client_golang/prometheus"
+	"strconv"
+	"sync"
+)
+
+// monitorStatusTracker remembers the status of every region of every monitoring job of one
+// account across collections to count the changes of the status.
+type monitorStatusTracker struct {
+	account  string
+	changes  *prometheus.CounterVec
+	lock     sync.Mutex
+	statuses map[string]model.MonitoringJobStatus
+}
+
+func newMonitorStatusTracker(account string, changes *prometheus.CounterVec) *monitorStatusTracker {
+	return &monitorStatusTracker{
+		account:  account,
+		changes:  changes,
+		statuses: map[string]model.MonitoringJobStatus{},
+	}
+}
+
+// track compares the given status with the status of the previous call. The first time a
+// status is seen it is only remembered.
+func (instance *monitorStatusTracker) track(job *model.MonitoringJob, region string, status *model.MonitoringJobStatus) {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	key := job.Id + " " + region
+	if previous, ok := instance.statuses[key]; ok {
+		if previous != *status {
+			instance.changes.WithLabelValues(instance.account, job.Name, region).Inc()
+		}
+	} else {
+		// Ensure the counter exists from the beginning to make rate() work.
+		instance.changes.WithLabelValues(instance.account, job.Name, region)
+	}
+	instance.statuses[key] = *status
+}
+
+func appendMonitoring(to *map[string]*prometheus.GaugeVec) {
+	appendGaugeWith(to, "monitor_info", "Information about monitoring jobs. Always 1.", "job", "type", "policy", "active")
+	appendGaugeWith(to, "monitor_up", "Is the monitoring job up in the region ('global' for the combined status of all regions)?", "job", "region")
+	appendGaugeWith(to, "monitor_status_since_timestamp_seconds", "Unix timestamp of the last change of the status of monitoring jobs by region.", "job", "region")
+	appendGaugeWith(to, "monitor_frequency_seconds", "Configured interval of the checks of monitoring jobs.", "job")
+}
+
+func appendMonitoringMetrics(to *map[string]*prometheus.GaugeVec) {
+	appendGaugeWith(to, "monitor_rtt_seconds", "Average round trip time of the checks of monitoring jobs in the last hour by region.", "job", "region")
+	appendGaugeWith(to, "monitor_connect_seconds", "Average connect time of the checks of monitoring jobs in the last hour by region.", "job", "region")
+	appendGaugeWith(to, "monitor_metric", "Average of other metrics of the checks of monitoring jobs in the last hour by region.", "job", "region", "metric")
+}
+
+// exportMonitoringIfRequired exports every monitoring job that matches the MonitorsFilter
+// by its name. The retrieval of the current status (and the metrics if MonitorMetrics is
+// enabled) of every of these jobs is submitted.
+func (instance *nsoneAccountExporter) exportMonitoringIfRequired(ctx context.Context, target *nsoneCollection) {
+	if !instance.settings.MonitorsFilter.HasValue() {
+		return
+	}
+	target.Submit(ctx, instance.workerPool, "monitoring", "/monitoring/jobs", "", func() error {
+		jobs, err := instance.client.GetMonitoringJobs(ctx)
+		if err != nil {
+			return err
+		}
+		for _, job := range *jobs {
+			if !instance.settings.MonitorsFilter.MatchString(job.Name) {
+				continue
+			}
+			if err := exportMonitoringJob(job, target); err != nil {
+				return err
+			}
+			instance.exportMonitoringJobStatus(ctx, job, target)
+			if instance.settings.MonitorMetrics {
+				instance.exportMonitoringJobMetrics(ctx, job, target)
+			}
+		}
+		return nil
+	})
+}
+
+func (instance *nsoneAccountExporter) exportMonitoringJobMetrics(ctx context.Context, job *model.MonitoringJob, target *nsoneCollection) {
+	target.Submit(ctx, instance.workerPool, "monitoring_metrics", "/monitoring/metrics/{job}", "", func() error {
+		metrics, err := instance.client.GetMonitoringJobMetrics(ctx, job.Id, model.P_HOURLY)
+		if err != nil {
+			return err
+		}
+		for region, metricsOfRegion := range metrics.Metrics {
+			for name, metric := range metricsOfRegion {
+				if metric == nil {
+					continue
+				}
+				labels := prometheus.Labels{
+					"job":    job.Name,
+					"region": region,
+				}
+				point, value := "monitor_metric", metric.Avg
+				switch name {
+				case model.MM_RTT:
+					point, value = "monitor_rtt_seconds", metric.Avg/1000
+				case model.MM_CONNECT:
+					point, value = "monitor_connect_seconds", metric.Avg/1000
+				default:
+					labels["metric"] = name
+				}
+				if err := target.setPointWith(point, value, labels); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func exportMonitoringJob(job *model.MonitoringJob, target *nsoneCollection) error {
+	err := target.setPointWith("monitor_info", 1, prometheus.Labels{
+		"job":    job.Name,
+		"type":   job.JobType,
+		"policy": job.Policy,
+		"active": strconv.FormatBool(job.Active),
+	})
+	if err != nil {
+		return err
+	}
+	return target.setPointWith("monitor_frequency_seconds", float64(job.Frequency), prometheus.Labels{
+		"job": job.Name,
+	})
+}
+
+// exportMonitoringJobStatus submits the retrieval of the current status of the given job by
+// region.
+func (instance *nsoneAccountExporter) exportMonitoringJobStatus(ctx context.Context, job *model.MonitoringJob, target *nsoneCollection) {
+	target.Submit(ctx, instance.workerPool, "monitoring", "/monitoring/jobs/{job}", "", func() error {
+		statuses, err := instance.client.GetMonitoringJobStatus(ctx, job.Id)
+		if err != nil {
+			return err
+		}
+		for region, status := range statuses {
+			if status == nil {
+				continue
+			}
+			labels := prometheus.Labels{
+				"job":    job.Name,
+				"region": region,
+			}
+			up := 0.0
+			if status.IsUp() {
+				up = 1
+			}
+			if err := target.setPointWith("monitor_up", up, labels); err != nil {
+				return err
+			}
+			if err := target.setPointWith("monitor_status_since_timestamp_seconds", float64(status.Since), labels); err != nil {
+				return err
+			}
+			instance.monitorStatusTracker.track(job, region, status)
+		}
+		return nil
+	})
+}
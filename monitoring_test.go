@@ -0,0 +1,129 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/echocat/nsone_exporter/model"
+)
+
+func newTestMonitoringJob(globalStatus string, globalSince int64) *model.MonitoringJob {
+	return &model.MonitoringJob{
+		Id:        "m1",
+		Name:      "web-check",
+		JobType:   "http",
+		Frequency: 60,
+		Policy:    "quorum",
+		Active:    true,
+		Status: model.MonitoringJobStatuses{
+			"global": {Since: globalSince, Status: globalStatus},
+			"lga":    {Since: 100, Status: "up"},
+		},
+	}
+}
+
+func TestCollectExportsStatusOfMatchingMonitoringJobsByRegion(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	server.AddMonitoringJob(newTestMonitoringJob("up", 100))
+	server.AddMonitoringJob(&model.MonitoringJob{Id: "m2", Name: "db-check"})
+	settings := newTestSettings()
+	settings.MonitorsFilter = model.NewRegexpOrPanic("^web")
+	exporter := NewNsoneExporter([]NsoneAccount{newTestAccount(t, server, "a", settings)})
+
+	samples := gather(t, exporter)
+
+	assertSamples(t, samples, map[string]float64{
+		`nsone_collector_success{account="a",collector="monitoring"}`:                               1,
+		`nsone_monitor_info{account="a",active="true",job="web-check",policy="quorum",type="http"}`: 1,
+		`nsone_monitor_frequency_seconds{account="a",job="web-check"}`:                              60,
+		`nsone_monitor_up{account="a",job="web-check",region="global"}`:                             1,
+		`nsone_monitor_up{account="a",job="web-check",region="lga"}`:                                1,
+		`nsone_monitor_status_since_timestamp_seconds{account="a",job="web-check",region="global"}`: 100,
+		`nsone_monitor_status_changes_total{account="a",job="web-check",region="global"}`:           0,
+		`nsone_monitor_status_changes_total{account="a",job="web-check",region="lga"}`:              0,
+	})
+	refuteSamples(t, samples, `nsone_monitor_info{account="a",active="false",job="db-check"`)
+	if requests := server.Requests("/monitoring/jobs/m1"); requests != 1 {
+		t.Errorf("Expected 1 request of the status of the matching job but got %d.", requests)
+	}
+	if requests := server.Requests("/monitoring/jobs/m2"); requests != 0 {
+		t.Errorf("Expected no request of the status of a not matching job but got %d.", requests)
+	}
+
+	server.AddMonitoringJob(newTestMonitoringJob("down", 200))
+	samples = gather(t, exporter)
+
+	assertSamples(t, samples, map[string]float64{
+		`nsone_monitor_up{account="a",job="web-check",region="global"}`:                             0,
+		`nsone_monitor_status_since_timestamp_seconds{account="a",job="web-check",region="global"}`: 200,
+		`nsone_monitor_status_changes_total{account="a",job="web-check",region="global"}`:           1,
+		`nsone_monitor_status_changes_total{account="a",job="web-check",region="lga"}`:              0,
+	})
+}
+
+func TestCollectMarksMonitoringAsFailedIfStatusCouldNotBeRetrieved(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	server.AddMonitoringJob(newTestMonitoringJob("up", 100))
+	server.NotFound("/monitoring/jobs/m1")
+	settings := newTestSettings()
+	settings.MonitorsFilter = model.NewRegexpOrPanic(".*")
+	exporter := NewNsoneExporter([]NsoneAccount{newTestAccount(t, server, "a", settings)})
+
+	samples := gather(t, exporter)
+
+	assertSamples(t, samples, map[string]float64{
+		`nsone_collector_success{account="a",collector="monitoring"}`:                               0,
+		`nsone_collect_errors_total{account="a",endpoint="/monitoring/jobs/{job}",zone=""}`:         1,
+		`nsone_monitor_info{account="a",active="true",job="web-check",policy="quorum",type="http"}`: 1,
+	})
+	refuteSamples(t, samples, "nsone_monitor_up")
+	refuteSamples(t, samples, "nsone_monitor_status_since_timestamp_seconds")
+}
+
+func TestCollectExportsMetricsOfMonitoringJobsIfRequired(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	server.AddMonitoringJob(newTestMonitoringJob("up", 100))
+	server.SetMonitoringJobMetrics("m1", map[string]map[string]*model.MonitoringMetric{
+		"lga": {
+			"rtt":     {Avg: 12},
+			"connect": {Avg: 3},
+			"loss":    {Avg: 0.5},
+		},
+	})
+	settings := newTestSettings()
+	settings.MonitorsFilter = model.NewRegexpOrPanic("^web")
+	settings.MonitorMetrics = true
+	exporter := NewNsoneExporter([]NsoneAccount{newTestAccount(t, server, "a", settings)})
+
+	samples := gather(t, exporter)
+
+	assertSamples(t, samples, map[string]float64{
+		`nsone_collector_success{account="a",collector="monitoring_metrics"}`:          1,
+		`nsone_monitor_rtt_seconds{account="a",job="web-check",region="lga"}`:          0.012,
+		`nsone_monitor_connect_seconds{account="a",job="web-check",region="lga"}`:      0.003,
+		`nsone_monitor_metric{account="a",job="web-check",metric="loss",region="lga"}`: 0.5,
+	})
+}
+
+func TestCollectMarksOnlyMonitoringMetricsAsFailedIfMetricsCouldNotBeRetrieved(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	server.AddMonitoringJob(newTestMonitoringJob("up", 100))
+	server.NotFound("/monitoring/metrics/m1")
+	settings := newTestSettings()
+	settings.MonitorsFilter = model.NewRegexpOrPanic(".*")
+	settings.MonitorMetrics = true
+	exporter := NewNsoneExporter([]NsoneAccount{newTestAccount(t, server, "a", settings)})
+
+	samples := gather(t, exporter)
+
+	assertSamples(t, samples, map[string]float64{
+		`nsone_collector_success{account="a",collector="monitoring"}`:                          1,
+		`nsone_collector_success{account="a",collector="monitoring_metrics"}`:                  0,
+		`nsone_collect_errors_total{account="a",endpoint="/monitoring/metrics/{job}",zone=""}`: 1,
+		`nsone_monitor_up{account="a",job="web-check",region="global"}`:                        1,
+	})
+	refuteSamples(t, samples, "nsone_monitor_rtt_seconds")
+}
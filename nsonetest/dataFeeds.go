@@ -0,0 +1,58 @@
+package nsonetest
+
+import (
+	"github.com/echocat/nsone_exporter/model"
+	"net/http"
+)
+
+// AddDataSource registers (or replaces) the given data source.
+func (instance *Server) AddDataSource(source *model.DataSource) {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	for i, candidate := range instance.dataSources {
+		if candidate.Id == source.Id {
+			instance.dataSources[i] = source
+			return
+		}
+	}
+	instance.dataSources = append(instance.dataSources, source)
+}
+
+// AddDataFeed registers (or replaces) the given feed of the data source with the given id.
+func (instance *Server) AddDataFeed(source string, feed *model.DataFeed) {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	for i, candidate := range instance.dataFeeds[source] {
+		if candidate.Id == feed.Id {
+			instance.dataFeeds[source][i] = feed
+			return
+		}
+	}
+	instance.dataFeeds[source] = append(instance.dataFeeds[source], feed)
+}
+
+func (instance *Server) serveDataSources(w http.ResponseWriter, parts []string) {
+	if len(parts) != 0 {
+		respondWithError(w, http.StatusNotFound, "Not found")
+		return
+	}
+	result := model.DataSources{}
+	result = append(result, instance.dataSources...)
+	respondWith(w, result)
+}
+
+func (instance *Server) serveDataFeeds(w http.ResponseWriter, parts []string) {
+	if len(parts) != 1 {
+		respondWithError(w, http.StatusNotFound, "Not found")
+		return
+	}
+	for _, source := range instance.dataSources {
+		if source.Id == parts[0] {
+			result := model.DataFeeds{}
+			result = append(result, instance.dataFeeds[source.Id]...)
+			respondWith(w, result)
+			return
+		}
+	}
+	respondWithError(w, http.StatusNotFound, "source not found")
+}
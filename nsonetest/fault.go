@@ -0,0 +1,79 @@
+package nsonetest
+
+import (
+	"net/http"
+	"time"
+)
+
+// AnyPath could be used as path of Inject to apply a fault to every request.
+const AnyPath = "*"
+
+// Fault describes how the Server should misbehave for a path.
+type Fault struct {
+	// StatusCode to respond with instead of the regular response. Ignored if <= 0.
+	StatusCode int
+	// Delay to wait before responding.
+	Delay time.Duration
+	// Malformed let the Server respond with a body that is not valid JSON.
+	Malformed bool
+	// Times the fault should be applied. If <= 0 it is applied forever.
+	Times int
+}
+
+// Inject registers the given fault for the given path (without /v1 prefix and query, for
+// example "/zones/example.com") or for AnyPath. A fault for a concrete path takes precedence.
+func (instance *Server) Inject(path string, fault Fault) {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	instance.faults[path] = &fault
+}
+
+// NotFound let every request of the given path fail with 404.
+func (instance *Server) NotFound(path string) {
+	instance.Inject(path, Fault{StatusCode: http.StatusNotFound})
+}
+
+// RateLimited let the given number of requests of the given path fail with 429.
+func (instance *Server) RateLimited(path string, times int) {
+	instance.Inject(path, Fault{StatusCode: http.StatusTooManyRequests, Times: times})
+}
+
+// Slow delays every response of the given path by delay.
+func (instance *Server) Slow(path string, delay time.Duration) {
+	instance.Inject(path, Fault{Delay: delay})
+}
+
+// Malformed let every request of the given path respond with a malformed body.
+func (instance *Server) Malformed(path string) {
+	instance.Inject(path, Fault{Malformed: true})
+}
+
+// ClearFaults removes all previously injected faults.
+func (instance *Server) ClearFaults() {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	instance.faults = map[string]*Fault{}
+}
+
+func (instance *Server) registerRequestAndSelectFaultFor(path string) *Fault {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	instance.requests[path]++
+	key := path
+	fault := instance.faults[key]
+	if fault == nil {
+		key = AnyPath
+		fault = instance.faults[key]
+	}
+	if fault == nil {
+		return nil
+	}
+	result := *fault
+	if fault.Times > 0 {
+		fault.Times--
+		if fault.Times <= 0 {
+			delete(instance.faults, key)
+		}
+	}
+	return &result
+}
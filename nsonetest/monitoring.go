@@ -0,0 +1,67 @@
+package nsonetest
+
+import (
+	"github.com/echocat/nsone_exporter/model"
+	"net/http"
+)
+
+// AddMonitoringJob registers (or replaces) the given monitoring job including its status.
+func (instance *Server) AddMonitoringJob(job *model.MonitoringJob) {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	for i, candidate := range instance.monitoringJobs {
+		if candidate.Id == job.Id {
+			instance.monitoringJobs[i] = job
+			return
+		}
+	}
+	instance.monitoringJobs = append(instance.monitoringJobs, job)
+}
+
+// SetMonitoringJobMetrics sets the metrics of the given monitoring job for every period.
+func (instance *Server) SetMonitoringJobMetrics(job string, metrics map[string]map[string]*model.MonitoringMetric) {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	instance.monitoringMetrics[job] = metrics
+}
+
+func (instance *Server) serveMonitoringMetrics(w http.ResponseWriter, r *http.Request, parts []string) {
+	var period model.StatsPeriod
+	if err := period.Set(r.URL.Query().Get("period")); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(parts) != 1 {
+		respondWithError(w, http.StatusNotFound, "Not found")
+		return
+	}
+	for _, job := range instance.monitoringJobs {
+		if job.Id == parts[0] {
+			respondWith(w, model.MonitoringJobMetrics{
+				JobId:   job.Id,
+				Metrics: instance.monitoringMetrics[job.Id],
+			})
+			return
+		}
+	}
+	respondWithError(w, http.StatusNotFound, "job not found")
+}
+
+func (instance *Server) serveMonitoringJobs(w http.ResponseWriter, parts []string) {
+	switch len(parts) {
+	case 0:
+		result := model.MonitoringJobs{}
+		result = append(result, instance.monitoringJobs...)
+		respondWith(w, result)
+	case 1:
+		for _, job := range instance.monitoringJobs {
+			if job.Id == parts[0] {
+				respondWith(w, job)
+				return
+			}
+		}
+		respondWithError(w, http.StatusNotFound, "job not found")
+	default:
+		respondWithError(w, http.StatusNotFound, "Not found")
+	}
+}
@@ -0,0 +1,94 @@
+package nsonetest
+
+import (
+	"github.com/echocat/nsone_exporter/model"
+	"net/http"
+)
+
+// AddPulsarApp registers (or replaces) the given Pulsar application.
+func (instance *Server) AddPulsarApp(app *model.PulsarApp) {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	for i, candidate := range instance.pulsarApps {
+		if candidate.Id == app.Id {
+			instance.pulsarApps[i] = app
+			return
+		}
+	}
+	instance.pulsarApps = append(instance.pulsarApps, app)
+}
+
+// AddPulsarJob registers the given job at the Pulsar application of job.AppId.
+func (instance *Server) AddPulsarJob(job *model.PulsarJob) {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	instance.pulsarJobs[job.AppId] = append(instance.pulsarJobs[job.AppId], job)
+}
+
+// SetPulsarJobPerformance sets the latency (in milliseconds) of the given job of the given
+// Pulsar application by geo region and ASN.
+func (instance *Server) SetPulsarJobPerformance(app string, job string, graph map[string]map[string][][]float64) {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	instance.pulsarGraphs[app+"/"+job+"/data"] = &model.PulsarJobGraph{Graph: graph}
+}
+
+// SetPulsarJobAvailability sets the availability (ratio 0..1) of the given job of the given
+// Pulsar application by geo region and ASN.
+func (instance *Server) SetPulsarJobAvailability(app string, job string, graph map[string]map[string][][]float64) {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	instance.pulsarGraphs[app+"/"+job+"/availability"] = &model.PulsarJobGraph{Graph: graph}
+}
+
+func (instance *Server) servePulsar(w http.ResponseWriter, r *http.Request, parts []string) {
+	switch {
+	case len(parts) == 0:
+		result := model.PulsarApps{}
+		result = append(result, instance.pulsarApps...)
+		respondWith(w, result)
+	case len(parts) == 2 && parts[1] == "jobs":
+		if !instance.hasPulsarApp(parts[0]) {
+			respondWithError(w, http.StatusNotFound, "app not found")
+			return
+		}
+		result := model.PulsarJobs{}
+		result = append(result, instance.pulsarJobs[parts[0]]...)
+		respondWith(w, result)
+	case len(parts) == 4 && parts[1] == "jobs" && (parts[3] == "data" || parts[3] == "availability"):
+		var period model.StatsPeriod
+		if err := period.Set(r.URL.Query().Get("period")); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if !instance.hasPulsarJob(parts[0], parts[2]) {
+			respondWithError(w, http.StatusNotFound, "job not found")
+			return
+		}
+		result := instance.pulsarGraphs[parts[0]+"/"+parts[2]+"/"+parts[3]]
+		if result == nil {
+			result = &model.PulsarJobGraph{Graph: map[string]map[string][][]float64{}}
+		}
+		respondWith(w, result)
+	default:
+		respondWithError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+func (instance *Server) hasPulsarApp(app string) bool {
+	for _, candidate := range instance.pulsarApps {
+		if candidate.Id == app {
+			return true
+		}
+	}
+	return false
+}
+
+func (instance *Server) hasPulsarJob(app string, job string) bool {
+	for _, candidate := range instance.pulsarJobs[app] {
+		if candidate.Id == job {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package nsonetest
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// rateLimit simulates the rate limit of NSONE: limit requests per period.
+type rateLimit struct {
+	limit      int
+	period     time.Duration
+	tokens     float64
+	lastRefill time.Time
+}
+
+// SetRateLimit let the Server limit the requests to limit per period and report the
+// limit with the X-RateLimit-* headers. Every request that exceeds the limit fails
+// with 429. A limit <= 0 disables the rate limit.
+func (instance *Server) SetRateLimit(limit int, period time.Duration) {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	if limit <= 0 {
+		instance.rateLimit = nil
+		return
+	}
+	instance.rateLimit = &rateLimit{
+		limit:      limit,
+		period:     period,
+		tokens:     float64(limit),
+		lastRefill: time.Now(),
+	}
+}
+
+// takeRateLimitToken sets the X-RateLimit-* headers and returns false if the limit is exceeded.
+func (instance *Server) takeRateLimitToken(w http.ResponseWriter) bool {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	limit := instance.rateLimit
+	if limit == nil {
+		return true
+	}
+	now := time.Now()
+	limit.tokens += float64(now.Sub(limit.lastRefill)) * float64(limit.limit) / float64(limit.period)
+	if limit.tokens > float64(limit.limit) {
+		limit.tokens = float64(limit.limit)
+	}
+	limit.lastRefill = now
+	allowed := limit.tokens >= 1
+	if allowed {
+		limit.tokens--
+	}
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit.limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(int(limit.tokens)))
+	w.Header().Set("X-RateLimit-Period", strconv.Itoa(int(limit.period/time.Second)))
+	return allowed
+}
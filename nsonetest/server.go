@@ -0,0 +1,397 @@
+// Package nsonetest provides a fake of the NSONE v1 API to run model.Client and
+// the exporter against without access to the real API.
+package nsonetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/echocat/nsone_exporter/model"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+const apiPrefix = "/v1"
+
+// Server is a fake of the NSONE v1 API. It serves the zones, usage, qps, pulsar, monitoring
+// and data feed resources from data that was registered before and is able to inject faults.
+type Server struct {
+	*httptest.Server
+	AccessToken string
+
+	lock              sync.RWMutex
+	zones             map[string]*model.Zone
+	zoneNames         []string
+	accountUsages     map[model.StatsPeriod]*model.Usage
+	zoneUsages        map[string]*model.Usage
+	recordUsages      map[string]*model.Usage
+	accountQps        float64
+	zoneQps           map[string]float64
+	recordQps         map[string]float64
+	faults            map[string]*Fault
+	requests          map[string]int
+	rateLimit         *rateLimit
+	pulsarApps        []*model.PulsarApp
+	pulsarJobs        map[string][]*model.PulsarJob
+	pulsarGraphs      map[string]*model.PulsarJobGraph
+	monitoringJobs    []*model.MonitoringJob
+	monitoringMetrics map[string]map[string]map[string]*model.MonitoringMetric
+	dataSources       []*model.DataSource
+	dataFeeds         map[string][]*model.DataFeed
+}
+
+// NewServer starts a new fake which only accepts requests with the given accessToken.
+// The caller should call Close when finished, to shut it down.
+func NewServer(accessToken string) *Server {
+	result := &Server{
+		AccessToken:       accessToken,
+		zones:             map[string]*model.Zone{},
+		accountUsages:     map[model.StatsPeriod]*model.Usage{},
+		zoneUsages:        map[string]*model.Usage{},
+		recordUsages:      map[string]*model.Usage{},
+		zoneQps:           map[string]float64{},
+		recordQps:         map[string]float64{},
+		faults:            map[string]*Fault{},
+		requests:          map[string]int{},
+		pulsarJobs:        map[string][]*model.PulsarJob{},
+		pulsarGraphs:      map[string]*model.PulsarJobGraph{},
+		monitoringMetrics: map[string]map[string]map[string]*model.MonitoringMetric{},
+		dataFeeds:         map[string][]*model.DataFeed{},
+	}
+	result.Server = httptest.NewServer(http.HandlerFunc(result.serve))
+	return result
+}
+
+// ApiUri returns the base URI of the fake API to be used as model.ClientOptions.ApiUri.
+func (instance *Server) ApiUri() string {
+	return instance.URL + apiPrefix
+}
+
+// ClientOptions returns options to create a model.Client that talks to this fake.
+func (instance *Server) ClientOptions() model.ClientOptions {
+	return model.ClientOptions{
+		ApiUri:                               instance.ApiUri(),
+		AccessToken:                          instance.AccessToken,
+		Timeout:                              5 * time.Second,
+		MaximumNumberOfConcurrentConnections: 10,
+	}
+}
+
+// NewClient creates a new model.Client that talks to this fake.
+func (instance *Server) NewClient() (*model.Client, error) {
+	return model.NewClient(instance.ClientOptions())
+}
+
+// AddZone registers (or replaces) the given zone including its records.
+func (instance *Server) AddZone(zone *model.Zone) {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	if _, exists := instance.zones[zone.Name]; !exists {
+		instance.zoneNames = append(instance.zoneNames, zone.Name)
+	}
+	instance.zones[zone.Name] = zone
+}
+
+// RemoveZone removes the zone with the given name and all of its stats.
+func (instance *Server) RemoveZone(name string) {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	delete(instance.zones, name)
+	for i, candidate := range instance.zoneNames {
+		if candidate == name {
+			instance.zoneNames = append(instance.zoneNames[:i], instance.zoneNames[i+1:]...)
+			break
+		}
+	}
+}
+
+// SetAccountUsage sets the usage of the whole account for the given period.
+func (instance *Server) SetAccountUsage(period model.StatsPeriod, queries float64, graph [][]float64) {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	instance.accountUsages[period] = &model.Usage{
+		Queries: queries,
+		Period:  period,
+		Graph:   graph,
+	}
+}
+
+// SetZoneUsage sets the usage of the given zone for the given period.
+func (instance *Server) SetZoneUsage(zone string, period model.StatsPeriod, queries float64, graph [][]float64) {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	instance.zoneUsages[usageKeyOf(period, zone, "", model.RT_NONE)] = &model.Usage{
+		Zone:    zone,
+		Queries: queries,
+		Period:  period,
+		Graph:   graph,
+	}
+}
+
+// SetRecordUsage sets the usage of the given record for the given period.
+func (instance *Server) SetRecordUsage(zone string, record string, recordType model.RecordType, period model.StatsPeriod, queries float64, graph [][]float64) {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	instance.recordUsages[usageKeyOf(period, zone, record, recordType)] = &model.Usage{
+		Zone:    zone,
+		Domain:  record,
+		Type:    recordType,
+		Queries: queries,
+		Period:  period,
+		Graph:   graph,
+	}
+}
+
+// SetAccountQps sets the queries per second of the whole account.
+func (instance *Server) SetAccountQps(qps float64) {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	instance.accountQps = qps
+}
+
+// SetZoneQps sets the queries per second of the given zone.
+func (instance *Server) SetZoneQps(zone string, qps float64) {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	instance.zoneQps[zone] = qps
+}
+
+// SetRecordQps sets the queries per second of the given record.
+func (instance *Server) SetRecordQps(zone string, record string, recordType model.RecordType, qps float64) {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	instance.recordQps[recordKeyOf(zone, record, recordType)] = qps
+}
+
+// Requests returns how often the given path (without /v1 prefix and query) was requested.
+func (instance *Server) Requests(path string) int {
+	instance.lock.RLock()
+	defer instance.lock.RUnlock()
+	return instance.requests[path]
+}
+
+func (instance *Server) serve(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, apiPrefix+"/") {
+		respondWithError(w, http.StatusNotFound, "Not found")
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, apiPrefix)
+	fault := instance.registerRequestAndSelectFaultFor(path)
+	if !instance.takeRateLimitToken(w) {
+		respondWithError(w, http.StatusTooManyRequests, "Rate limit exceeded")
+		return
+	}
+	if fault != nil {
+		if fault.Delay > 0 {
+			time.Sleep(fault.Delay)
+		}
+		if fault.StatusCode > 0 {
+			respondWithError(w, fault.StatusCode, http.StatusText(fault.StatusCode))
+			return
+		}
+		if fault.Malformed {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"this is": not json`))
+			return
+		}
+	}
+	if r.Header.Get("X-NSONE-Key") != instance.AccessToken {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	if r.Method != "GET" {
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	instance.lock.RLock()
+	defer instance.lock.RUnlock()
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case parts[0] == "zones":
+		instance.serveZones(w, parts[1:])
+	case len(parts) >= 2 && parts[0] == "stats" && parts[1] == "usage":
+		instance.serveUsage(w, r, parts[2:])
+	case len(parts) >= 2 && parts[0] == "stats" && parts[1] == "qps":
+		instance.serveQps(w, parts[2:])
+	case len(parts) >= 2 && parts[0] == "pulsar" && parts[1] == "apps":
+		instance.servePulsar(w, r, parts[2:])
+	case len(parts) >= 2 && parts[0] == "monitoring" && parts[1] == "jobs":
+		instance.serveMonitoringJobs(w, parts[2:])
+	case len(parts) >= 2 && parts[0] == "monitoring" && parts[1] == "metrics":
+		instance.serveMonitoringMetrics(w, r, parts[2:])
+	case len(parts) >= 2 && parts[0] == "data" && parts[1] == "sources":
+		instance.serveDataSources(w, parts[2:])
+	case len(parts) >= 2 && parts[0] == "data" && parts[1] == "feeds":
+		instance.serveDataFeeds(w, parts[2:])
+	default:
+		respondWithError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+func (instance *Server) serveZones(w http.ResponseWriter, parts []string) {
+	switch len(parts) {
+	case 0:
+		result := model.Zones{}
+		for _, name := range instance.zoneNames {
+			zone := *instance.zones[name]
+			zone.Records = nil
+			result = append(result, &zone)
+		}
+		respondWith(w, result)
+	case 1:
+		if zone, ok := instance.zones[parts[0]]; ok {
+			// Like NSONE the records of a zone contain only the short answers.
+			result := *zone
+			result.Records = []*model.Record{}
+			for _, record := range zone.Records {
+				shortRecord := *record
+				shortRecord.Answers = nil
+				result.Records = append(result.Records, &shortRecord)
+			}
+			respondWith(w, result)
+		} else {
+			respondWithError(w, http.StatusNotFound, "zone not found")
+		}
+	case 3:
+		if record := instance.recordOf(parts[0], parts[1], parts[2]); record != nil {
+			respondWith(w, fullRecordOf(record))
+		} else {
+			respondWithError(w, http.StatusNotFound, "record not found")
+		}
+	default:
+		respondWithError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+func (instance *Server) serveUsage(w http.ResponseWriter, r *http.Request, parts []string) {
+	var period model.StatsPeriod
+	if err := period.Set(r.URL.Query().Get("period")); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	expand := r.URL.Query().Get("expand") == "true"
+	switch len(parts) {
+	case 0:
+		if expand {
+			result := model.Usages{}
+			for _, name := range instance.zoneNames {
+				result = append(result, instance.zoneUsageOf(period, name))
+			}
+			respondWith(w, result)
+		} else {
+			usage := instance.accountUsages[period]
+			if usage == nil {
+				usage = &model.Usage{Period: period}
+			}
+			respondWith(w, model.Usages{usage})
+		}
+	case 1:
+		zone, ok := instance.zones[parts[0]]
+		if !ok {
+			respondWithError(w, http.StatusNotFound, "zone not found")
+		} else if expand {
+			result := model.Usages{}
+			for _, record := range zone.Records {
+				result = append(result, instance.recordUsageOf(period, zone.Name, record.Name, record.Type))
+			}
+			respondWith(w, result)
+		} else {
+			respondWith(w, model.Usages{instance.zoneUsageOf(period, zone.Name)})
+		}
+	case 3:
+		if record := instance.recordOf(parts[0], parts[1], parts[2]); record != nil {
+			respondWith(w, model.Usages{instance.recordUsageOf(period, parts[0], record.Name, record.Type)})
+		} else {
+			respondWithError(w, http.StatusNotFound, "record not found")
+		}
+	default:
+		respondWithError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+func (instance *Server) serveQps(w http.ResponseWriter, parts []string) {
+	switch len(parts) {
+	case 0:
+		respondWith(w, model.QpsStat{Qps: instance.accountQps})
+	case 1:
+		if _, ok := instance.zones[parts[0]]; ok {
+			respondWith(w, model.QpsStat{Qps: instance.zoneQps[parts[0]]})
+		} else {
+			respondWithError(w, http.StatusNotFound, "zone not found")
+		}
+	case 3:
+		if record := instance.recordOf(parts[0], parts[1], parts[2]); record != nil {
+			respondWith(w, model.QpsStat{Qps: instance.recordQps[recordKeyOf(parts[0], record.Name, record.Type)]})
+		} else {
+			respondWithError(w, http.StatusNotFound, "record not found")
+		}
+	default:
+		respondWithError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+func (instance *Server) recordOf(zoneName string, recordName string, plainRecordType string) *model.Record {
+	zone, ok := instance.zones[zoneName]
+	if !ok {
+		return nil
+	}
+	for _, record := range zone.Records {
+		if record.Name == recordName && strings.EqualFold(string(record.Type), plainRecordType) {
+			return record
+		}
+	}
+	return nil
+}
+
+func (instance *Server) zoneUsageOf(period model.StatsPeriod, zone string) *model.Usage {
+	if usage, ok := instance.zoneUsages[usageKeyOf(period, zone, "", model.RT_NONE)]; ok {
+		return usage
+	}
+	return &model.Usage{Zone: zone, Period: period}
+}
+
+func (instance *Server) recordUsageOf(period model.StatsPeriod, zone string, record string, recordType model.RecordType) *model.Usage {
+	if usage, ok := instance.recordUsages[usageKeyOf(period, zone, record, recordType)]; ok {
+		return usage
+	}
+	return &model.Usage{Zone: zone, Domain: record, Type: recordType, Period: period}
+}
+
+// fullRecordOf returns the given record. If it has no answers they are derived from its short answers.
+func fullRecordOf(record *model.Record) *model.Record {
+	if len(record.Answers) > 0 {
+		return record
+	}
+	result := *record
+	for _, shortAnswer := range record.ShortAnswers {
+		result.Answers = append(result.Answers, &model.Answer{Answer: strings.Fields(shortAnswer)})
+	}
+	return &result
+}
+
+func recordKeyOf(zone string, record string, recordType model.RecordType) string {
+	return fmt.Sprintf("%s/%s/%s", zone, record, string(recordType))
+}
+
+func usageKeyOf(period model.StatsPeriod, zone string, record string, recordType model.RecordType) string {
+	return string(period) + ":" + recordKeyOf(zone, record, recordType)
+}
+
+func respondWith(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(value)
+	if err != nil {
+		panic(fmt.Sprintf("Could not encode %v. Got: %v", value, err))
+	}
+}
+
+func respondWithError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": message,
+	})
+}
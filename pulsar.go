@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"github.com/echocat/nsone_exporter/model"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func appendPulsar(to *map[string]*prometheus.GaugeVec) {
+	appendGaugeWith(to, "pulsar_job_info", "Information about Pulsar jobs. Always 1.", "app", "job", "type", "active")
+	appendGaugeWith(to, "pulsar_job_latency_seconds", "Median latency of Pulsar jobs measured by real users in the last hour by geo region ('*' for all regions).", "app", "job", "geo")
+	appendGaugeWith(to, "pulsar_job_availability_ratio", "Availability (0..1) of Pulsar jobs measured by real users in the last hour by geo region ('*' for all regions).", "app", "job", "geo")
+}
+
+// exportPulsarIfRequired retrieves all Pulsar apps and their jobs and exports every job
+// that matches '<appName> <jobName>' including its latency and availability.
+func (instance *nsoneAccountExporter) exportPulsarIfRequired(ctx context.Context, target *nsoneCollection) {
+	if !instance.settings.PulsarFilter.HasValue() {
+		return
+	}
+	target.Submit(ctx, instance.workerPool, "pulsar", "/pulsar/apps", "", func() error {
+		apps, err := instance.client.GetPulsarApps(ctx)
+		if err != nil {
+			return err
+		}
+		for _, app := range *apps {
+			instance.exportPulsarApp(ctx, app, target)
+		}
+		return nil
+	})
+}
+
+// exportPulsarApp submits the retrieval of the jobs of the given app.
+func (instance *nsoneAccountExporter) exportPulsarApp(ctx context.Context, app *model.PulsarApp, target *nsoneCollection) {
+	target.Submit(ctx, instance.workerPool, "pulsar", "/pulsar/apps/{app}/jobs", "", func() error {
+		jobs, err := instance.client.GetPulsarJobs(ctx, app.Id)
+		if err != nil {
+			return err
+		}
+		for _, job := range *jobs {
+			if instance.settings.PulsarFilter.MatchString(app.Name + " " + job.Name) {
+				if err := exportPulsarJob(app, job, target); err != nil {
+					return err
+				}
+				instance.exportPulsarJobPerformance(ctx, app, job, target)
+			}
+		}
+		return nil
+	})
+}
+
+func exportPulsarJob(app *model.PulsarApp, job *model.PulsarJob, target *nsoneCollection) error {
+	active := "false"
+	if job.Active {
+		active = "true"
+	}
+	return target.setPointWith("pulsar_job_info", 1, prometheus.Labels{
+		"app":    app.Name,
+		"job":    job.Name,
+		"type":   job.Type,
+		"active": active,
+	})
+}
+
+// exportPulsarJobPerformance submits the retrieval of the latency and the availability of
+// the given job.
+func (instance *nsoneAccountExporter) exportPulsarJobPerformance(ctx context.Context, app *model.PulsarApp, job *model.PulsarJob, target *nsoneCollection) {
+	target.Submit(ctx, instance.workerPool, "pulsar", "/pulsar/apps/{app}/jobs/{job}/data", "", func() error {
+		graph, err := instance.client.GetPulsarJobPerformance(ctx, app.Id, job.Id, model.P_HOURLY)
+		if err != nil {
+			return err
+		}
+		return exportPulsarJobGraph("pulsar_job_latency_seconds", 0.001, app, job, graph, target)
+	})
+	target.Submit(ctx, instance.workerPool, "pulsar", "/pulsar/apps/{app}/jobs/{job}/availability", "", func() error {
+		graph, err := instance.client.GetPulsarJobAvailability(ctx, app.Id, job.Id, model.P_HOURLY)
+		if err != nil {
+			return err
+		}
+		return exportPulsarJobGraph("pulsar_job_availability_ratio", 1, app, job, graph, target)
+	})
+}
+
+// exportPulsarJobGraph sets the last value of every geo region of the given graph multiplied
+// by factor as point with the given name.
+func exportPulsarJobGraph(name string, factor float64, app *model.PulsarApp, job *model.PulsarJob, graph *model.PulsarJobGraph, target *nsoneCollection) error {
+	for geo, value := range graph.LastValuesByGeo() {
+		err := target.setPointWith(name, value*factor, prometheus.Labels{
+			"app": app.Name,
+			"job": job.Name,
+			"geo": geo,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
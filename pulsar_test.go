@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/echocat/nsone_exporter/model"
+)
+
+func TestCollectExportsMatchingPulsarJobs(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	server.AddPulsarApp(&model.PulsarApp{Id: "app1", Name: "web", Active: true})
+	server.AddPulsarJob(&model.PulsarJob{Id: "job1", AppId: "app1", Name: "cdn-a", Type: "latency", Active: true})
+	server.AddPulsarJob(&model.PulsarJob{Id: "job2", AppId: "app1", Name: "cdn-b", Type: "latency"})
+	server.AddPulsarJob(&model.PulsarJob{Id: "job3", AppId: "app1", Name: "origin", Type: "custom"})
+	server.SetPulsarJobPerformance("app1", "job1", map[string]map[string][][]float64{
+		"*":  {"*": {{1500000000, 80}, {1500003600, 50}}},
+		"US": {"*": {{1500000000, 40}, {1500003600, 30}}, "7018": {{1500003600, 20}}},
+		"EU": {"*": {}},
+	})
+	server.SetPulsarJobAvailability("app1", "job1", map[string]map[string][][]float64{
+		"*":  {"*": {{1500003600, 0.99}}},
+		"US": {"*": {{1500003600, 0.95}}},
+	})
+	server.SetPulsarJobPerformance("app1", "job3", map[string]map[string][][]float64{
+		"*": {"*": {{1500003600, 10}}},
+	})
+	settings := newTestSettings()
+	settings.PulsarFilter = model.NewRegexpOrPanic("^web cdn-")
+	exporter := NewNsoneExporter([]NsoneAccount{newTestAccount(t, server, "a", settings)})
+
+	samples := gather(t, exporter)
+
+	assertSamples(t, samples, map[string]float64{
+		`nsone_collector_success{account="a",collector="pulsar"}`:                                1,
+		`nsone_pulsar_job_info{account="a",active="true",app="web",job="cdn-a",type="latency"}`:  1,
+		`nsone_pulsar_job_info{account="a",active="false",app="web",job="cdn-b",type="latency"}`: 1,
+		`nsone_pulsar_job_latency_seconds{account="a",app="web",geo="*",job="cdn-a"}`:            0.05,
+		`nsone_pulsar_job_latency_seconds{account="a",app="web",geo="US",job="cdn-a"}`:           0.03,
+		`nsone_pulsar_job_availability_ratio{account="a",app="web",geo="*",job="cdn-a"}`:         0.99,
+		`nsone_pulsar_job_availability_ratio{account="a",app="web",geo="US",job="cdn-a"}`:        0.95,
+	})
+	refuteSamples(t, samples, `nsone_pulsar_job_info{account="a",active="false",app="web",job="origin"`)
+	refuteSamples(t, samples, `nsone_pulsar_job_latency_seconds{account="a",app="web",geo="EU"`)
+	refuteSamples(t, samples, `nsone_pulsar_job_latency_seconds{account="a",app="web",geo="*",job="cdn-b"}`)
+	refuteSamples(t, samples, `nsone_pulsar_job_latency_seconds{account="a",app="web",geo="*",job="origin"}`)
+	if requests := server.Requests("/pulsar/apps/app1/jobs/job3/data"); requests != 0 {
+		t.Errorf("Expected no request for the data of a not matching job but got %d.", requests)
+	}
+}
+
+func TestCollectMarksPulsarAsFailedIfJobsCouldNotBeRetrieved(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	server.AddPulsarApp(&model.PulsarApp{Id: "app1", Name: "web"})
+	server.NotFound("/pulsar/apps/app1/jobs")
+	settings := newTestSettings()
+	settings.PulsarFilter = model.NewRegexpOrPanic(".*")
+	exporter := NewNsoneExporter([]NsoneAccount{newTestAccount(t, server, "a", settings)})
+
+	samples := gather(t, exporter)
+
+	assertSamples(t, samples, map[string]float64{
+		`nsone_up{account="a"}`:                                                              1,
+		`nsone_collector_success{account="a",collector="pulsar"}`:                            0,
+		`nsone_collect_errors_total{account="a",endpoint="/pulsar/apps/{app}/jobs",zone=""}`: 1,
+	})
+}
+
+func TestCollectMarksPulsarAsFailedIfAvailabilityCouldNotBeRetrieved(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	server.AddPulsarApp(&model.PulsarApp{Id: "app1", Name: "web"})
+	server.AddPulsarJob(&model.PulsarJob{Id: "job1", AppId: "app1", Name: "cdn-a", Type: "latency", Active: true})
+	server.SetPulsarJobPerformance("app1", "job1", map[string]map[string][][]float64{
+		"*": {"*": {{1500003600, 50}}},
+	})
+	server.NotFound("/pulsar/apps/app1/jobs/job1/availability")
+	settings := newTestSettings()
+	settings.PulsarFilter = model.NewRegexpOrPanic(".*")
+	exporter := NewNsoneExporter([]NsoneAccount{newTestAccount(t, server, "a", settings)})
+
+	samples := gather(t, exporter)
+
+	assertSamples(t, samples, map[string]float64{
+		`nsone_collector_success{account="a",collector="pulsar"}`:                                               0,
+		`nsone_collect_errors_total{account="a",endpoint="/pulsar/apps/{app}/jobs/{job}/availability",zone=""}`: 1,
+		`nsone_pulsar_job_info{account="a",active="true",app="web",job="cdn-a",type="latency"}`:                 1,
+		`nsone_pulsar_job_latency_seconds{account="a",app="web",geo="*",job="cdn-a"}`:                           0.05,
+	})
+	refuteSamples(t, samples, "nsone_pulsar_job_availability_ratio")
+}
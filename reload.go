@@ -0,0 +1,91 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// configReloader re-creates all accounts of an NsoneExporter from -config.file and the
+// flags. It is triggered by SIGHUP or a POST on /-/reload.
+type configReloader struct {
+	exporter *NsoneExporter
+	lock     sync.Mutex
+
+	lastReloadSuccessful       prometheus.Gauge
+	lastReloadSuccessTimestamp prometheus.Gauge
+	reloadFailures             prometheus.Counter
+}
+
+func newConfigReloader(exporter *NsoneExporter) *configReloader {
+	result := &configReloader{
+		exporter: exporter,
+		lastReloadSuccessful: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "config_last_reload_successful",
+			Help:      "Was the last configuration reload successful?",
+		}),
+		lastReloadSuccessTimestamp: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "config_last_reload_success_timestamp_seconds",
+			Help:      "Unix timestamp of the last successful configuration reload.",
+		}),
+		reloadFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "config_reload_failures_total",
+			Help:      "Number of failed configuration reloads.",
+		}),
+	}
+	result.lastReloadSuccessful.Set(1)
+	result.lastReloadSuccessTimestamp.Set(float64(time.Now().UnixNano()) / 1e9)
+	return result
+}
+
+// Reload re-creates all accounts. If this fails the exporter keeps the previous accounts.
+func (instance *configReloader) Reload() error {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+
+	log.Info("Reloading configuration...")
+	accounts, err := createAccounts()
+	if err != nil {
+		log.Errorf("Reloading configuration... FAILED! Got: %v", err)
+		instance.lastReloadSuccessful.Set(0)
+		instance.reloadFailures.Inc()
+		return err
+	}
+	instance.exporter.Reload(accounts)
+	instance.lastReloadSuccessful.Set(1)
+	instance.lastReloadSuccessTimestamp.Set(float64(time.Now().UnixNano()) / 1e9)
+	log.Infof("Reloading configuration... DONE! (accounts: %d)", len(accounts))
+	return nil
+}
+
+// ReloadOnSignal calls Reload every time the process receives SIGHUP.
+func (instance *configReloader) ReloadOnSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		for range signals {
+			instance.Reload()
+		}
+	}()
+}
+
+// Describe implements prometheus.Collector.
+func (instance *configReloader) Describe(ch chan<- *prometheus.Desc) {
+	instance.lastReloadSuccessful.Describe(ch)
+	instance.lastReloadSuccessTimestamp.Describe(ch)
+	instance.reloadFailures.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (instance *configReloader) Collect(ch chan<- prometheus.Metric) {
+	instance.lastReloadSuccessful.Collect(ch)
+	instance.lastReloadSuccessTimestamp.Collect(ch)
+	instance.reloadFailures.Collect(ch)
+}
@@ -1,11 +1,16 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
+	"fmt"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/prometheus/common/log"
 	slog "log"
 	"net/http"
+	"strconv"
+	"time"
 	"github.com/echocat/nsone_exporter/utils"
 )
 
@@ -21,12 +26,61 @@ func createHttpServerLogWrapper() *slog.Logger {
 	return slog.New(&bufferedLogWriter{}, "", 0)
 }
 
-func startServer(metricsPath, listenAddress, tlsCert, tlsPrivateKey, tlsClientCa string) error {
+// newMetricsHandler creates a handler that delivers the metrics of the default registry
+// together with the metrics of the given exporter. If the scrape provides its timeout
+// with the X-Prometheus-Scrape-Timeout-Seconds header, fetching of the stats from NSONE
+// is cancelled timeoutOffset (but at most half of the timeout) before this timeout and
+// everything complete until then is delivered. The same happens if the scrape is abandoned.
+func newMetricsHandler(exporter *NsoneExporter, timeoutOffset time.Duration) http.Handler {
+	return prometheus.InstrumentHandler("prometheus", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if plainTimeout := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds"); len(plainTimeout) > 0 {
+			timeout, err := strconv.ParseFloat(plainTimeout, 64)
+			if err != nil || timeout <= 0 {
+				http.Error(w, fmt.Sprintf("Illegal X-Prometheus-Scrape-Timeout-Seconds header: %s", plainTimeout), http.StatusBadRequest)
+				return
+			}
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, scrapeDeadlineOf(time.Duration(timeout*float64(time.Second)), timeoutOffset))
+			defer cancel()
+		}
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(exporter.WithContext(ctx))
+		gatherers := prometheus.Gatherers{prometheus.DefaultGatherer, registry}
+		promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{
+			ErrorLog:      log.NewErrorLogger(),
+			ErrorHandling: promhttp.ContinueOnError,
+		}).ServeHTTP(w, r)
+	}))
+}
+
+// scrapeDeadlineOf returns how long a scrape with the given timeout could fetch stats from
+// NSONE. This is the timeout minus timeoutOffset but at least the half of the timeout.
+func scrapeDeadlineOf(timeout time.Duration, timeoutOffset time.Duration) time.Duration {
+	if result := timeout - timeoutOffset; result >= timeout/2 {
+		return result
+	}
+	return timeout / 2
+}
+
+func startServer(metricsPath, listenAddress, tlsCert, tlsPrivateKey, tlsClientCa string, metrics http.Handler, reload func() error) error {
 	server := &http.Server{
 		Addr:     listenAddress,
 		ErrorLog: createHttpServerLogWrapper(),
 	}
-	http.Handle(metricsPath, prometheus.Handler())
+	http.Handle(metricsPath, metrics)
+	http.HandleFunc("/-/reload", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "Only POST requests allowed.", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := reload(); err != nil {
+			http.Error(w, fmt.Sprintf("Could not reload configuration. Got: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("OK\n"))
+	})
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(`<html>
              <head><title>NSONE Exporter</title></head>
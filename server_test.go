@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestScrapeDeadlineOf(t *testing.T) {
+	cases := []struct {
+		timeout  time.Duration
+		offset   time.Duration
+		expected time.Duration
+	}{
+		{10 * time.Second, 500 * time.Millisecond, 9500 * time.Millisecond},
+		{time.Second, 500 * time.Millisecond, 500 * time.Millisecond},
+		{500 * time.Millisecond, 500 * time.Millisecond, 250 * time.Millisecond},
+		{200 * time.Millisecond, 500 * time.Millisecond, 100 * time.Millisecond},
+		{time.Second, 0, time.Second},
+	}
+	for _, c := range cases {
+		if actual := scrapeDeadlineOf(c.timeout, c.offset); actual != c.expected {
+			t.Errorf("Expected deadline of timeout %v with offset %v to be %v but got %v.", c.timeout, c.offset, c.expected, actual)
+		}
+	}
+}
+
+func TestMetricsHandlerRejectsIllegalScrapeTimeouts(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	handler := newMetricsHandler(NewNsoneExporter([]NsoneAccount{newTestAccount(t, server, "a", newTestSettings())}), 500*time.Millisecond)
+
+	for _, timeout := range []string{"0", "-1", "foo"} {
+		request := httptest.NewRequest("GET", "/metrics", nil)
+		request.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", timeout)
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, request)
+		if response.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for scrape timeout %s but got %d.", timeout, response.Code)
+		}
+	}
+}
+
+func TestMetricsHandlerCollectsWithShortScrapeTimeouts(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	handler := newMetricsHandler(NewNsoneExporter([]NsoneAccount{newTestAccount(t, server, "a", newTestSettings())}), 500*time.Millisecond)
+	request := httptest.NewRequest("GET", "/metrics", nil)
+	request.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", "0.5")
+	response := httptest.NewRecorder()
+
+	handler.ServeHTTP(response, request)
+
+	if response.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 but got %d.", response.Code)
+	}
+	if requests := server.Requests("/zones"); requests != 1 {
+		t.Errorf("Expected zones to be requested once but got %d requests.", requests)
+	}
+}
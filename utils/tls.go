@@ -2,6 +2,7 @@ package utils
 
 import (
 	"crypto/x509"
+	"fmt"
 	"io/ioutil"
 )
 
@@ -21,4 +22,14 @@ func LoadCertificatesFrom(pemFile string) (*x509.CertPool, error) {
 	return certificates, nil
 }
 
+func AppendCertificatesFrom(certificates *x509.CertPool, pemFile string) error {
+	caCert, err := ioutil.ReadFile(pemFile)
+	if err != nil {
+		return err
+	}
+	if !certificates.AppendCertsFromPEM(caCert) {
+		return fmt.Errorf("%s does not contain any PEM encoded certificate.", pemFile)
+	}
+	return nil
+}
 
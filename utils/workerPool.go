@@ -1,12 +1,14 @@
 package utils
 
 import (
+	"context"
 	"runtime"
 	"sync"
 )
 
 type WorkerPool struct {
-	futures chan *WorkerFuture
+	futures   chan *WorkerFuture
+	closeOnce sync.Once
 }
 
 func NewWorkerPool(numberOfWorkers int, queueSize int) *WorkerPool {
@@ -21,16 +23,32 @@ func NewWorkerPool(numberOfWorkers int, queueSize int) *WorkerPool {
 	return result
 }
 
+// Close stops the workers of this pool after the already enqueued tasks. It could be called
+// multiple times.
 func (instance *WorkerPool) Close() {
-	close(instance.futures)
+	instance.closeOnce.Do(func() {
+		close(instance.futures)
+	})
 }
 
-func (instance *WorkerPool) Submit(task WorkerTask) *WorkerFuture {
-	future := NewWorkerFutureFor(task)
-	instance.futures <- future
+// Submit enqueues the given task. If ctx is done before the task is started it is not
+// executed at all and the future fails with the error of ctx.
+func (instance *WorkerPool) Submit(ctx context.Context, task WorkerTask) *WorkerFuture {
+	future := NewWorkerFutureFor(ctx, task)
+	instance.Enqueue(future)
 	return future
 }
 
+// Enqueue enqueues the given (not yet executed) future. If the context of the future is
+// done before it is started it is not executed at all and fails with the error of this context.
+func (instance *WorkerPool) Enqueue(future *WorkerFuture) {
+	select {
+	case instance.futures <- future:
+	case <-future.ctx.Done():
+		future.Execute()
+	}
+}
+
 func finalizeWorkerPool(instance *WorkerPool) {
 	instance.Close()
 }
@@ -48,27 +66,26 @@ func (instance *WorkerPool) handle(future *WorkerFuture) {
 type WorkerTask func() error
 
 type WorkerFuture struct {
-	Func      WorkerTask
-	condition *sync.Cond
-	done      bool
-	err       error
+	Func WorkerTask
+	ctx  context.Context
+	done chan struct{}
+	err  error
 }
 
 type WorkerFutures []*WorkerFuture
 
 func (instance *WorkerFuture) Execute() error {
-	instance.condition.L.Lock()
-	defer func() {
-		instance.done = true
-		instance.condition.Broadcast()
-		instance.condition.L.Unlock()
-	}()
-	instance.err = instance.Func()
+	defer close(instance.done)
+	if err := instance.ctx.Err(); err != nil {
+		instance.err = err
+	} else {
+		instance.err = instance.Func()
+	}
 	return instance.err
 }
 
-func (instance *WorkerFutures) Submit(pool *WorkerPool, task WorkerTask) *WorkerFuture {
-	future := pool.Submit(task)
+func (instance *WorkerFutures) Submit(ctx context.Context, pool *WorkerPool, task WorkerTask) *WorkerFuture {
+	future := pool.Submit(ctx, task)
 	instance.Append(future)
 	return future
 }
@@ -78,9 +95,11 @@ func (instance *WorkerFutures) Append(future *WorkerFuture) *WorkerFutures {
 	return instance
 }
 
-func (instance *WorkerFutures) Wait() error {
+// Wait waits for all futures and returns the error of the first failed future. If ctx is
+// done before all futures are done the error of ctx is returned.
+func (instance *WorkerFutures) Wait(ctx context.Context) error {
 	for _, future := range *instance {
-		err := future.Wait()
+		err := future.Wait(ctx)
 		if err != nil {
 			return err
 		}
@@ -88,20 +107,52 @@ func (instance *WorkerFutures) Wait() error {
 	return nil
 }
 
-func NewWorkerFutureFor(task WorkerTask) *WorkerFuture {
+// WaitAll waits for all futures (also if some of them fail) and returns the errors
+// of all failed futures. If ctx is done before all futures are done every future that
+// is still outstanding fails with the error of ctx.
+func (instance *WorkerFutures) WaitAll(ctx context.Context) []error {
+	errs := []error{}
+	for _, future := range *instance {
+		err := future.Wait(ctx)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// NewWorkerFutureFor creates a new future for the given task. If ctx is done before the
+// future is executed the task is not executed at all.
+func NewWorkerFutureFor(ctx context.Context, task WorkerTask) *WorkerFuture {
 	return &WorkerFuture{
 		Func: task,
-		condition: &sync.Cond{
-			L: &sync.Mutex{},
-		},
+		ctx:  ctx,
+		done: make(chan struct{}),
 	}
 }
 
-func (instance *WorkerFuture) Wait() error {
-	instance.condition.L.Lock()
-	defer instance.condition.L.Unlock()
-	if !instance.done {
-		instance.condition.Wait()
+// Wait waits until the future is done and returns its error. If ctx is done before the
+// error of ctx is returned.
+func (instance *WorkerFuture) Wait(ctx context.Context) error {
+	select {
+	case <-instance.done:
+		return instance.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Done returns a channel that is closed after the future was executed.
+func (instance *WorkerFuture) Done() <-chan struct{} {
+	return instance.done
+}
+
+// IsDone returns true if the future was executed.
+func (instance *WorkerFuture) IsDone() bool {
+	select {
+	case <-instance.done:
+		return true
+	default:
+		return false
 	}
-	return instance.err
 }
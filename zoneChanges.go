@@ -0,0 +1,131 @@
+package main
+
+import (
+	"github.com/echocat/nsone_exporter/model"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// zoneChangeTracker remembers the serial and the records of every zone of one account
+// across collections to detect changes of zones.
+type zoneChangeTracker struct {
+	account string
+	changes *prometheus.CounterVec
+	lock    sync.Mutex
+	zones   map[string]*trackedZone
+}
+
+type trackedZone struct {
+	serial     int64
+	records    map[string]string
+	lastChange time.Time
+}
+
+func newZoneChangeTracker(account string, changes *prometheus.CounterVec) *zoneChangeTracker {
+	return &zoneChangeTracker{
+		account: account,
+		changes: changes,
+		zones:   map[string]*trackedZone{},
+	}
+}
+
+// track compares the given zones with the zones of the previous call. The first time a zone
+// is seen it is only remembered. Zones which are neither contained in zones nor in
+// unavailableZones (because they could not be retrieved) were deleted and are forgotten.
+// If logChanges is true the records that were added, removed or changed are logged. The
+// timestamp of the last change is only exported for zones that match inventoryFilter.
+func (instance *zoneChangeTracker) track(zones *model.Zones, unavailableZones []string, inventoryFilter model.Matcher, logChanges bool, target *nsoneCollection) {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	now := time.Now()
+	existing := map[string]bool{}
+	for _, zone := range unavailableZones {
+		existing[zone] = true
+	}
+	for _, zone := range *zones {
+		existing[zone.Name] = true
+		current := &trackedZone{
+			serial:  zone.Serial,
+			records: recordStatesOf(zone),
+		}
+		if previous, ok := instance.zones[zone.Name]; ok {
+			current.lastChange = previous.lastChange
+			if previous.serial != current.serial {
+				current.lastChange = now
+				instance.changes.WithLabelValues(instance.account, zone.Name).Inc()
+				if logChanges {
+					instance.logChanges(zone.Name, previous, current)
+				}
+			}
+		} else {
+			// Ensure the counter exists from the beginning to make rate() work.
+			instance.changes.WithLabelValues(instance.account, zone.Name)
+		}
+		instance.zones[zone.Name] = current
+		if !current.lastChange.IsZero() && inventoryFilter.HasValue() && inventoryFilter.MatchString(zone.Name) {
+			err := target.setPointWith("zone_last_change_timestamp_seconds", float64(current.lastChange.UnixNano())/1e9, prometheus.Labels{
+				"zone": zone.Name,
+			})
+			if err != nil {
+				target.failed("zone_inventory", "/zones/{zone}", zone.Name, err)
+			}
+		}
+	}
+	for zone := range instance.zones {
+		if !existing[zone] {
+			delete(instance.zones, zone)
+			instance.changes.DeleteLabelValues(instance.account, zone)
+		}
+	}
+}
+
+func (instance *zoneChangeTracker) logChanges(zone string, previous *trackedZone, current *trackedZone) {
+	logger := log.With("account", instance.account).
+		With("zone", zone).
+		With("previousSerial", previous.serial).
+		With("serial", current.serial)
+	logger.Info("Zone changed.")
+	for _, record := range sortedKeysOf(previous.records) {
+		if _, ok := current.records[record]; !ok {
+			logger.With("change", "removed").With("record", record).With("previous", previous.records[record]).Info("Record of zone changed.")
+		}
+	}
+	for _, record := range sortedKeysOf(current.records) {
+		previousState, ok := previous.records[record]
+		if !ok {
+			logger.With("change", "added").With("record", record).With("current", current.records[record]).Info("Record of zone changed.")
+		} else if previousState != current.records[record] {
+			logger.With("change", "changed").With("record", record).With("previous", previousState).With("current", current.records[record]).Info("Record of zone changed.")
+		}
+	}
+}
+
+// recordStatesOf returns a comparable state (TTL and answers) by '<recordType> <recordName>'
+// of every record of the given zone.
+func recordStatesOf(zone *model.Zone) map[string]string {
+	result := map[string]string{}
+	for _, record := range zone.Records {
+		answers := append([]string{}, record.ShortAnswers...)
+		sort.Strings(answers)
+		state := "ttl=" + strconv.Itoa(record.TTL) + " answers=[" + strings.Join(answers, ", ") + "]"
+		if len(record.Link) > 0 {
+			state += " link=" + record.Link
+		}
+		result[record.Type.String()+" "+record.Name] = state
+	}
+	return result
+}
+
+func sortedKeysOf(m map[string]string) []string {
+	result := []string{}
+	for key := range m {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/echocat/nsone_exporter/model"
+)
+
+func TestCollectTracksZoneChangesWithoutInventory(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	exporter := NewNsoneExporter([]NsoneAccount{newTestAccount(t, server, "a", newTestSettings())})
+	gather(t, exporter)
+	server.AddZone(&model.Zone{Name: "example.com", TTL: 3600, Serial: 6})
+
+	samples := gather(t, exporter)
+
+	assertSamples(t, samples, map[string]float64{
+		`nsone_zone_changes_total{account="a",zone="example.com"}`: 1,
+		`nsone_zone_changes_total{account="a",zone="other.org"}`:   0,
+	})
+	refuteSamples(t, samples, "nsone_zone_last_change_timestamp_seconds")
+}
+
+func TestCollectForgetsDeletedZones(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	settings := newTestSettings()
+	settings.InventoryOfZonesFilter = model.NewRegexpOrPanic(".*")
+	exporter := NewNsoneExporter([]NsoneAccount{newTestAccount(t, server, "a", settings)})
+	gather(t, exporter)
+	server.AddZone(&model.Zone{Name: "example.com", TTL: 3600, Serial: 6})
+	server.NotFound("/zones/other.org")
+
+	samples := gather(t, exporter)
+
+	if _, ok := samples[`nsone_zone_last_change_timestamp_seconds{account="a",zone="example.com"}`]; !ok {
+		t.Errorf("Expected the timestamp of the last change of example.com but there is none.")
+	}
+	assertSamples(t, samples, map[string]float64{
+		`nsone_zone_changes_total{account="a",zone="example.com"}`: 1,
+		// other.org could only not be retrieved. It still exists.
+		`nsone_zone_changes_total{account="a",zone="other.org"}`: 0,
+	})
+
+	server.ClearFaults()
+	server.RemoveZone("example.com")
+	samples = gather(t, exporter)
+
+	refuteSamples(t, samples, `nsone_zone_changes_total{account="a",zone="example.com"}`, `nsone_zone_last_change_timestamp_seconds`)
+	assertSamples(t, samples, map[string]float64{
+		`nsone_zone_changes_total{account="a",zone="other.org"}`: 0,
+	})
+}
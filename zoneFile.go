@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/echocat/nsone_exporter/model"
+	"github.com/echocat/nsone_exporter/utils"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// zoneFileTypes are all record types that could be represented in a RFC 1035 master file.
+// Records of other types (like ALIAS) are NSONE specific and only written as comments.
+var zoneFileTypes = map[model.RecordType]bool{
+	model.RT_A:     true,
+	model.RT_AAAA:  true,
+	model.RT_AFSDB: true,
+	model.RT_CAA:   true,
+	model.RT_CERT:  true,
+	model.RT_CNAME: true,
+	model.RT_DNAME: true,
+	model.RT_DS:    true,
+	model.RT_HINFO: true,
+	model.RT_HTTPS: true,
+	model.RT_MX:    true,
+	model.RT_NAPTR: true,
+	model.RT_NS:    true,
+	model.RT_PTR:   true,
+	model.RT_RP:    true,
+	model.RT_SPF:   true,
+	model.RT_SRV:   true,
+	model.RT_SOA:   true,
+	model.RT_SSHFP: true,
+	model.RT_SVCB:  true,
+	model.RT_TLSA:  true,
+	model.RT_TXT:   true,
+}
+
+// zoneFileRecord is one resource record of a zone file in master file presentation.
+type zoneFileRecord struct {
+	// Name is fully qualified (ends with a dot).
+	Name string
+	TTL  int
+	Type model.RecordType
+	Data string
+}
+
+func (instance zoneFileRecord) String() string {
+	return fmt.Sprintf("%s\t%d\tIN\t%s\t%s", instance.Name, instance.TTL, instance.Type, instance.Data)
+}
+
+// zoneFile is the content of a zone in master file presentation.
+type zoneFile struct {
+	Zone    string
+	Serial  int64
+	TTL     int
+	Records []zoneFileRecord
+	// Comments are written at the end, for example records which are NSONE specific.
+	Comments []string
+}
+
+// retrieveZoneFile retrieves the given zone including the full answers of every record.
+func retrieveZoneFile(ctx context.Context, account NsoneAccount, zoneName string) (*zoneFile, error) {
+	zone, err := account.Client.GetZone(ctx, zoneName)
+	if err != nil {
+		return nil, fmt.Errorf("Could not retrieve zone %s of account %s. Cause: %v", zoneName, account.Name, err)
+	}
+	records := make([]*model.Record, len(zone.Records))
+	pool := utils.NewWorkerPool(account.NumberOfWorkers, account.NumberOfWorkers)
+	defer pool.Close()
+	futures := utils.WorkerFutures{}
+	for i, record := range zone.Records {
+		i, record := i, record
+		if len(record.Link) > 0 {
+			records[i] = record
+			continue
+		}
+		futures.Submit(ctx, pool, func() error {
+			fullRecord, err := account.Client.GetRecord(ctx, zone.Name, record.Name, record.Type)
+			if err != nil {
+				return fmt.Errorf("Could not retrieve record %v %s of zone %s. Cause: %v", record.Type, record.Name, zone.Name, err)
+			}
+			records[i] = fullRecord
+			return nil
+		})
+	}
+	if err := futures.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return newZoneFile(zone, records), nil
+}
+
+func newZoneFile(zone *model.Zone, records []*model.Record) *zoneFile {
+	result := &zoneFile{
+		Zone:   fqdn(zone.Name),
+		Serial: zone.Serial,
+		TTL:    zone.TTL,
+	}
+	result.Records = append(result.Records, zoneFileRecord{
+		Name: fqdn(zone.Name),
+		TTL:  zone.TTL,
+		Type: model.RT_SOA,
+		Data: fmt.Sprintf("%s %s %d %d %d %d %d", soaPrimaryOf(zone, records), soaMailboxOf(zone), zone.Serial, zone.Refresh, zone.Retry, zone.Expiry, zone.NxTTL),
+	})
+	others := []zoneFileRecord{}
+	for _, record := range records {
+		if len(record.Link) > 0 {
+			result.Comments = append(result.Comments, fmt.Sprintf("%s\t%d\tIN\t%s\t; linked to %s", fqdn(record.Name), record.TTL, record.Type, record.Link))
+			continue
+		}
+		for _, zfr := range zoneFileRecordsOf(record) {
+			if zoneFileTypes[record.Type] {
+				others = append(others, zfr)
+			} else {
+				result.Comments = append(result.Comments, zfr.String()+"\t; not supported by master files")
+			}
+		}
+	}
+	sortZoneFileRecords(others)
+	result.Records = append(result.Records, others...)
+	sort.Strings(result.Comments)
+	return result
+}
+
+// soaPrimaryOf returns the name server that is written as primary into the SOA record of
+// the given zone: the primary master, the first DNS server or the first name server of the
+// apex. If none of them is known the apex itself is used because the name must not be empty.
+func soaPrimaryOf(zone *model.Zone, records []*model.Record) string {
+	if len(zone.PrimaryMaster) > 0 {
+		return fqdn(zone.PrimaryMaster)
+	}
+	if len(zone.DnsServers) > 0 && len(zone.DnsServers[0]) > 0 {
+		return fqdn(zone.DnsServers[0])
+	}
+	for _, record := range records {
+		if record.Type == model.RT_NS && len(record.Link) <= 0 && fqdn(record.Name) == fqdn(zone.Name) {
+			for _, elements := range answersOf(record) {
+				if len(elements) > 0 && len(elements[0]) > 0 {
+					return fqdn(elements[0])
+				}
+			}
+		}
+	}
+	return fqdn(zone.Name)
+}
+
+// soaMailboxOf returns the hostmaster of the given zone (like 'dns.admin@example.com') as
+// domain name (like 'dns\.admin.example.com.') how it is written into the SOA record. If
+// there is no hostmaster 'hostmaster.<zone>.' is used.
+func soaMailboxOf(zone *model.Zone) string {
+	if len(zone.Hostmaster) <= 0 {
+		return "hostmaster." + fqdn(zone.Name)
+	}
+	at := strings.LastIndex(zone.Hostmaster, "@")
+	if at < 0 {
+		return fqdn(zone.Hostmaster)
+	}
+	local := strings.Replace(zone.Hostmaster[:at], ".", `\.`, -1)
+	return fqdn(local + "." + zone.Hostmaster[at+1:])
+}
+
+// writeTo writes this zone as RFC 1035 master file to w.
+func (instance *zoneFile) writeTo(w io.Writer) error {
+	buffered := bufio.NewWriter(w)
+	fmt.Fprintf(buffered, "; Zone %s exported from NSONE (serial: %d)\n", instance.Zone, instance.Serial)
+	fmt.Fprintf(buffered, "$ORIGIN %s\n", instance.Zone)
+	fmt.Fprintf(buffered, "$TTL %d\n", instance.TTL)
+	for _, record := range instance.Records {
+		fmt.Fprintln(buffered, record.String())
+	}
+	for _, comment := range instance.Comments {
+		fmt.Fprintf(buffered, "; %s\n", comment)
+	}
+	return buffered.Flush()
+}
+
+// zoneFileRecordsOf returns one zoneFileRecord for every answer of the given (not linked) record.
+func zoneFileRecordsOf(record *model.Record) []zoneFileRecord {
+	result := []zoneFileRecord{}
+	for _, elements := range answersOf(record) {
+		result = append(result, zoneFileRecord{
+			Name: fqdn(record.Name),
+			TTL:  record.TTL,
+			Type: record.Type,
+			Data: zoneFileDataOf(record.Type, elements),
+		})
+	}
+	return result
+}
+
+// answersOf returns the elements of every answer of the given record. If the record does not
+// contain full answers (like the records of zone details) they are derived from the short answers.
+func answersOf(record *model.Record) [][]string {
+	result := [][]string{}
+	if len(record.Answers) > 0 {
+		for _, answer := range record.Answers {
+			result = append(result, answer.Answer)
+		}
+		return result
+	}
+	for _, shortAnswer := range record.ShortAnswers {
+		result = append(result, elementsOfShortAnswer(record.Type, shortAnswer))
+	}
+	return result
+}
+
+func elementsOfShortAnswer(recordType model.RecordType, shortAnswer string) []string {
+	if recordType == model.RT_TXT || recordType == model.RT_SPF {
+		return []string{shortAnswer}
+	}
+	return strings.Fields(shortAnswer)
+}
+
+// zoneFileNameElements are the elements of answers by record type that are domain names.
+var zoneFileNameElements = map[model.RecordType][]int{
+	model.RT_CNAME: {0},
+	model.RT_DNAME: {0},
+	model.RT_NS:    {0},
+	model.RT_PTR:   {0},
+	model.RT_ALIAS: {0},
+	model.RT_MX:    {1},
+	model.RT_AFSDB: {1},
+	model.RT_RP:    {0, 1},
+	model.RT_SRV:   {3},
+	model.RT_NAPTR: {5},
+	model.RT_HTTPS: {1},
+	model.RT_SVCB:  {1},
+}
+
+// zoneFileDataOf renders the elements of an answer as rdata in master file presentation.
+func zoneFileDataOf(recordType model.RecordType, elements []string) string {
+	quoted := map[model.RecordType][]int{
+		model.RT_CAA:   {2},
+		model.RT_HINFO: {0, 1},
+		model.RT_NAPTR: {2, 3, 4},
+	}
+	result := append([]string{}, elements...)
+	switch recordType {
+	case model.RT_TXT, model.RT_SPF:
+		for i, element := range result {
+			result[i] = quoteCharacterString(element)
+		}
+	default:
+		for _, i := range zoneFileNameElements[recordType] {
+			if i < len(result) {
+				result[i] = fqdn(result[i])
+			}
+		}
+		for _, i := range quoted[recordType] {
+			if i < len(result) {
+				result[i] = quoteCharacterString(result[i])
+			}
+		}
+	}
+	return strings.Join(result, " ")
+}
+
+// quoteCharacterString quotes the given value. Values longer than 255 characters are split
+// into multiple strings like required for TXT records.
+func quoteCharacterString(value string) string {
+	parts := []string{}
+	for len(value) > 255 {
+		parts = append(parts, value[:255])
+		value = value[255:]
+	}
+	parts = append(parts, value)
+	for i, part := range parts {
+		part = strings.Replace(part, `\`, `\\`, -1)
+		part = strings.Replace(part, `"`, `\"`, -1)
+		parts[i] = `"` + part + `"`
+	}
+	return strings.Join(parts, " ")
+}
+
+func fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+func sortZoneFileRecords(records []zoneFileRecord) {
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Name != records[j].Name {
+			return records[i].Name < records[j].Name
+		}
+		if records[i].Type != records[j].Type {
+			return records[i].Type < records[j].Type
+		}
+		return records[i].Data < records[j].Data
+	})
+}
+
+// selectAccount returns the account with the given name. If name is empty and there is
+// exactly one account, this account is returned.
+func selectAccount(accounts []NsoneAccount, name string) (NsoneAccount, error) {
+	if len(name) <= 0 {
+		if len(accounts) == 1 {
+			return accounts[0], nil
+		}
+		return NsoneAccount{}, fmt.Errorf("There are %d accounts. Select one of them.", len(accounts))
+	}
+	for _, account := range accounts {
+		if account.Name == name {
+			return account, nil
+		}
+	}
+	return NsoneAccount{}, fmt.Errorf("There is no account '%s'.", name)
+}
+
+// exportZone writes the given zone of the given account as RFC 1035 master file to output
+// (stdout if empty or '-').
+func exportZone(ctx context.Context, account NsoneAccount, zoneName string, output string) error {
+	zone, err := retrieveZoneFile(ctx, account, zoneName)
+	if err != nil {
+		return err
+	}
+	if len(output) <= 0 || output == "-" {
+		return zone.writeTo(os.Stdout)
+	}
+	return writeFileAtomically(output, zone.writeTo)
+}
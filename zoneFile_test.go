@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/echocat/nsone_exporter/model"
+)
+
+func renderZoneFile(t *testing.T, zone *model.Zone, records ...*model.Record) string {
+	buffer := &bytes.Buffer{}
+	if err := newZoneFile(zone, records).writeTo(buffer); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.String()
+}
+
+func TestZoneFileRendersZoneAsMasterFile(t *testing.T) {
+	zone := &model.Zone{Name: "example.com", Serial: 5, TTL: 3600, Refresh: 1, Retry: 2, Expiry: 3, NxTTL: 4, PrimaryMaster: "dns1.p01.nsone.net", Hostmaster: "hostmaster@nsone.net"}
+
+	actual := renderZoneFile(t, zone,
+		&model.Record{Name: "www.example.com", Type: model.RT_A, TTL: 60, Answers: []*model.Answer{{Answer: []string{"2.2.2.2"}}, {Answer: []string{"1.1.1.1"}}}},
+		&model.Record{Name: "example.com", Type: model.RT_ALIAS, TTL: 60, Answers: []*model.Answer{{Answer: []string{"lb.example.net"}}}},
+		&model.Record{Name: "old.example.com", Type: model.RT_CNAME, TTL: 60, Link: "new.example.com"},
+	)
+
+	expected := "; Zone example.com. exported from NSONE (serial: 5)\n" +
+		"$ORIGIN example.com.\n" +
+		"$TTL 3600\n" +
+		"example.com.\t3600\tIN\tSOA\tdns1.p01.nsone.net. hostmaster.nsone.net. 5 1 2 3 4\n" +
+		"www.example.com.\t60\tIN\tA\t1.1.1.1\n" +
+		"www.example.com.\t60\tIN\tA\t2.2.2.2\n" +
+		"; example.com.\t60\tIN\tALIAS\tlb.example.net.\t; not supported by master files\n" +
+		"; old.example.com.\t60\tIN\tCNAME\t; linked to new.example.com\n"
+	if actual != expected {
+		t.Errorf("Expected:\n%s\nbut got:\n%s", expected, actual)
+	}
+}
+
+func TestZoneFileQuotesAndEscapesCharacterStrings(t *testing.T) {
+	cases := []struct {
+		recordType model.RecordType
+		elements   []string
+		expected   string
+	}{
+		{model.RT_TXT, []string{`v=spf1 include:example.net -all`}, `"v=spf1 include:example.net -all"`},
+		{model.RT_TXT, []string{`say "hello"`}, `"say \"hello\""`},
+		{model.RT_TXT, []string{`back\slash`}, `"back\\slash"`},
+		{model.RT_TXT, []string{"first", "second"}, `"first" "second"`},
+		{model.RT_TXT, []string{strings.Repeat("a", 300)}, `"` + strings.Repeat("a", 255) + `" "` + strings.Repeat("a", 45) + `"`},
+		{model.RT_SPF, []string{`v=spf1 -all`}, `"v=spf1 -all"`},
+		{model.RT_HINFO, []string{"INTEL", `"LINUX"`}, `"INTEL" "\"LINUX\""`},
+		{model.RT_CAA, []string{"0", "issue", "letsencrypt.org"}, `0 issue "letsencrypt.org"`},
+	}
+	for _, c := range cases {
+		if actual := zoneFileDataOf(c.recordType, c.elements); actual != c.expected {
+			t.Errorf("Expected %v %v to be rendered as %s but got: %s", c.recordType, c.elements, c.expected, actual)
+		}
+	}
+}
+
+func TestZoneFileQualifiesDomainNames(t *testing.T) {
+	cases := []struct {
+		recordType model.RecordType
+		elements   []string
+		expected   string
+	}{
+		{model.RT_CNAME, []string{"www.example.com"}, "www.example.com."},
+		{model.RT_CNAME, []string{"www.example.com."}, "www.example.com."},
+		{model.RT_NS, []string{"dns1.p01.nsone.net"}, "dns1.p01.nsone.net."},
+		{model.RT_MX, []string{"10", "mx.example.com"}, "10 mx.example.com."},
+		{model.RT_SRV, []string{"10", "20", "5060", "sip.example.com"}, "10 20 5060 sip.example.com."},
+		{model.RT_A, []string{"1.2.3.4"}, "1.2.3.4"},
+	}
+	for _, c := range cases {
+		if actual := zoneFileDataOf(c.recordType, c.elements); actual != c.expected {
+			t.Errorf("Expected %v %v to be rendered as %s but got: %s", c.recordType, c.elements, c.expected, actual)
+		}
+	}
+	records := zoneFileRecordsOf(&model.Record{Name: "www.example.com", Type: model.RT_A, TTL: 60, ShortAnswers: []string{"1.2.3.4"}})
+	if len(records) != 1 || records[0].Name != "www.example.com." {
+		t.Errorf("Expected the fully qualified name of the record but got: %v", records)
+	}
+}
+
+func TestZoneFileConvertsHostmasterToMailbox(t *testing.T) {
+	cases := map[string]string{
+		"hostmaster@example.com": "hostmaster.example.com.",
+		"dns.admin@example.com":  `dns\.admin.example.com.`,
+		"hostmaster.example.com": "hostmaster.example.com.",
+		"":                       "hostmaster.example.com.",
+	}
+	for hostmaster, expected := range cases {
+		if actual := soaMailboxOf(&model.Zone{Name: "example.com", Hostmaster: hostmaster}); actual != expected {
+			t.Errorf("Expected hostmaster '%s' to be rendered as %s but got: %s", hostmaster, expected, actual)
+		}
+	}
+}
+
+func TestZoneFileUsesNameServersIfThereIsNoPrimary(t *testing.T) {
+	nameServers := &model.Record{Name: "example.com", Type: model.RT_NS, TTL: 60, ShortAnswers: []string{"ns1.example.net", "ns2.example.net"}}
+	cases := []struct {
+		zone     *model.Zone
+		records  []*model.Record
+		expected string
+	}{
+		{&model.Zone{Name: "example.com", PrimaryMaster: "dns1.p01.nsone.net", DnsServers: []string{"dns2.p01.nsone.net"}}, nil, "dns1.p01.nsone.net."},
+		{&model.Zone{Name: "example.com", DnsServers: []string{"dns2.p01.nsone.net"}}, []*model.Record{nameServers}, "dns2.p01.nsone.net."},
+		{&model.Zone{Name: "example.com"}, []*model.Record{nameServers}, "ns1.example.net."},
+		{&model.Zone{Name: "example.com"}, nil, "example.com."},
+	}
+	for _, c := range cases {
+		if actual := soaPrimaryOf(c.zone, c.records); actual != c.expected {
+			t.Errorf("Expected primary %s for %+v but got: %s", c.expected, c.zone, actual)
+		}
+	}
+	if actual := renderZoneFile(t, &model.Zone{Name: "example.com", TTL: 60}); strings.Contains(actual, "SOA\t. ") {
+		t.Errorf("Expected no empty primary in:\n%s", actual)
+	}
+}